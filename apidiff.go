@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wanjm/gos/astinfo"
+)
+
+// apiDiff 实现gos apidiff，比较当前代码的接口和之前的文档；
+// 返回值作为退出码：0没有不兼容修改，1有不兼容修改，2执行失败；
+func apiDiff(args []string) int {
+	flags := flag.NewFlagSet("apidiff", flag.ExitOnError)
+	var path, base, rev, format, loader string
+	flags.StringVar(&path, "p", ".", "需要比较的工程的根目录")
+	flags.StringVar(&base, "base", "", "基准文档，相对工程根目录，默认为SwaggerCfg.Output")
+	flags.StringVar(&rev, "rev", "HEAD", "从git的该版本中读取基准文档，为空时读取工作区中的文件")
+	flags.StringVar(&format, "format", "text", "输出格式，text或json")
+	flags.StringVar(&loader, "loader", "", "解析方式，ast或packages，默认使用配置中的Loader")
+	logLevel := flags.String("log-level", "warning", "输出的诊断信息的最低级别，error，warning或info")
+	flags.Parse(args)
+	if err := setupDiagnostics(*logLevel, "text"); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	// 诊断信息和生成过程中的日志输出到stderr，保证stdout中只有比较结果
+	astinfo.Diagnostics.Output = os.Stderr
+	project, err := loadProject(path, "", loader, cacheDefault)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	result, err := project.ApiDiff(base, rev)
+	astinfo.Diagnostics.Summary(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "apidiff failed with %s\n", err.Error())
+		return 2
+	}
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	case "text":
+		result.WriteText(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", format)
+		return 2
+	}
+	if len(result.Breaking) > 0 {
+		return 1
+	}
+	return 0
+}
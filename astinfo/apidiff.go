@@ -0,0 +1,523 @@
+package astinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v3"
+)
+
+// gos apidiff 比较当前代码生成的文档和之前提交的文档，找出不兼容的修改；
+// 两份文档都转换为3.1格式再比较，所以基准文档可以是2.0或3.1，json或yaml；
+// 请求中的字段，收紧是不兼容的（新增required字段，enum减少）；响应中的字段，放宽是不兼容的（删除字段，enum增加，变为nullable）；
+
+const (
+	ChangeBreaking    = "breaking"
+	ChangeNonBreaking = "non-breaking"
+)
+
+// ApiChange 一处接口修改
+type ApiChange struct {
+	Kind     string `json:"kind"`
+	Endpoint string `json:"endpoint"`           // 如 POST /api/user/get
+	Location string `json:"location,omitempty"` // 如 request.body.name
+	Message  string `json:"message"`
+}
+
+// ApiDiffResult 比较结果，按照接口和位置排序
+type ApiDiffResult struct {
+	Breaking    []ApiChange `json:"breaking"`
+	NonBreaking []ApiChange `json:"nonBreaking"`
+}
+
+// 请求和响应中的schema，兼容性的判断方向相反
+type diffDirection int
+
+const (
+	diffRequest diffDirection = iota
+	diffResponse
+)
+
+type apiDiffer struct {
+	oldDoc, newDoc *OpenAPI
+	changes        []ApiChange
+	endpoint       string
+	// 已经比较过的ref，防止递归结构体无限比较；
+	seen map[string]bool
+}
+
+// ApiDiff 比较当前工程的文档和base文件；rev不为空时从git的rev版本中读取base；
+func (mp *MainProject) ApiDiff(base, rev string) (*ApiDiffResult, error) {
+	cfg := &mp.Cfg.SwaggerCfg
+	if base == "" {
+		base = cfg.Output
+		if base == "" {
+			base = defaultSwaggerOutput
+		}
+	}
+	content, err := readApiBase(mp.currentProject.Path, base, rev)
+	if err != nil {
+		return nil, err
+	}
+	oldDoc, err := parseApiDoc(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", base, err)
+	}
+	swagger := NewSwagger(mp)
+	swagger.build("")
+	differ := &apiDiffer{
+		oldDoc: oldDoc,
+		newDoc: convertToOpenAPI31(swagger.swag, ""),
+		seen:   make(map[string]bool),
+	}
+	differ.diff()
+	return differ.result(), nil
+}
+
+func readApiBase(projectPath, base, rev string) ([]byte, error) {
+	if rev == "" {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(projectPath, base)
+		}
+		return os.ReadFile(base)
+	}
+	// git show 的路径需要相对于仓库根目录；base可能是绝对路径，工程也可能在仓库的子目录中
+	root, err := gitOutput(projectPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(projectPath, base)
+	}
+	name, err := relativePath(strings.TrimSpace(string(root)), base)
+	if err != nil {
+		return nil, err
+	}
+	return gitOutput(projectPath, "show", rev+":"+name)
+}
+
+// relativePath 返回path相对root的路径，使用/分隔；路径中有符号链接(如macOS的/tmp)时先解析；path不在root中时返回错误
+func relativePath(root, path string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	// path在rev中存在，工作区中可能已经删除，只解析所在的目录
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in git repository %s", path, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	content, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return content, nil
+}
+
+// parseApiDoc 解析2.0或3.1的文档，统一转换为3.1；3.1中servers的url加回到path中，与2.0一致；
+func parseApiDoc(content []byte) (*OpenAPI, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	// yaml解析后重新生成json，统一使用json的解析
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := raw["openapi"]; !ok {
+		var swag spec.Swagger
+		if err := json.Unmarshal(content, &swag); err != nil {
+			return nil, err
+		}
+		return convertToOpenAPI31(&swag, ""), nil
+	}
+	var doc OpenAPI
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Servers) > 0 && strings.HasPrefix(doc.Servers[0].Url, "/") {
+		prefix := strings.TrimSuffix(doc.Servers[0].Url, "/")
+		paths := make(map[string]*OpenAPIPathItem, len(doc.Paths))
+		for url, item := range doc.Paths {
+			paths[prefix+url] = item
+		}
+		doc.Paths = paths
+	}
+	return &doc, nil
+}
+
+func (d *apiDiffer) add(kind, location, format string, args ...any) {
+	d.changes = append(d.changes, ApiChange{
+		Kind:     kind,
+		Endpoint: d.endpoint,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *apiDiffer) result() *ApiDiffResult {
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		a, b := d.changes[i], d.changes[j]
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		return a.Location < b.Location
+	})
+	result := &ApiDiffResult{Breaking: []ApiChange{}, NonBreaking: []ApiChange{}}
+	for _, change := range d.changes {
+		if change.Kind == ChangeBreaking {
+			result.Breaking = append(result.Breaking, change)
+		} else {
+			result.NonBreaking = append(result.NonBreaking, change)
+		}
+	}
+	return result
+}
+
+func operations(item *OpenAPIPathItem) map[string]*OpenAPIOperation {
+	result := make(map[string]*OpenAPIOperation)
+	if item == nil {
+		return result
+	}
+	for method, op := range map[string]*OpenAPIOperation{
+		GET: item.Get, PUT: item.Put, POST: item.Post, DELETE: item.Delete,
+		OPTIONS: item.Options, HEAD: item.Head, PATCH: item.Patch,
+	} {
+		if op != nil {
+			result[method] = op
+		}
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *apiDiffer) diff() {
+	for _, url := range sortedKeys(d.oldDoc.Paths) {
+		oldOps := operations(d.oldDoc.Paths[url])
+		newOps := operations(d.newDoc.Paths[url])
+		for _, method := range sortedKeys(oldOps) {
+			d.endpoint = method + " " + url
+			newOp, ok := newOps[method]
+			if !ok {
+				if len(newOps) > 0 {
+					d.add(ChangeBreaking, "", "method changed from %s to %s", method, strings.Join(sortedKeys(newOps), ","))
+				} else {
+					d.add(ChangeBreaking, "", "endpoint removed")
+				}
+				continue
+			}
+			d.diffOperation(oldOps[method], newOp)
+		}
+	}
+	for _, url := range sortedKeys(d.newDoc.Paths) {
+		oldOps := operations(d.oldDoc.Paths[url])
+		for _, method := range sortedKeys(operations(d.newDoc.Paths[url])) {
+			if _, ok := oldOps[method]; !ok {
+				d.endpoint = method + " " + url
+				d.add(ChangeNonBreaking, "", "endpoint added")
+			}
+		}
+	}
+}
+
+func (d *apiDiffer) diffOperation(oldOp, newOp *OpenAPIOperation) {
+	params := func(op *OpenAPIOperation) map[string]OpenAPIParameter {
+		result := make(map[string]OpenAPIParameter)
+		for _, param := range op.Parameters {
+			result[param.In+"."+param.Name] = param
+		}
+		return result
+	}
+	oldParams, newParams := params(oldOp), params(newOp)
+	for _, key := range sortedKeys(newParams) {
+		newParam := newParams[key]
+		oldParam, ok := oldParams[key]
+		location := "request." + key
+		switch {
+		case !ok && newParam.Required:
+			d.add(ChangeBreaking, location, "new required parameter")
+		case !ok:
+			d.add(ChangeNonBreaking, location, "parameter added")
+		case newParam.Required && !oldParam.Required:
+			d.add(ChangeBreaking, location, "parameter became required")
+		}
+		if ok && oldParam.Schema != nil && newParam.Schema != nil {
+			d.diffSchema(location, *oldParam.Schema, *newParam.Schema, diffRequest)
+		}
+	}
+	for _, key := range sortedKeys(oldParams) {
+		if _, ok := newParams[key]; !ok {
+			d.add(ChangeNonBreaking, "request."+key, "parameter removed")
+		}
+	}
+	oldBody, newBody := bodySchema(oldOp.RequestBody), bodySchema(newOp.RequestBody)
+	switch {
+	case oldBody == nil && newBody != nil && newOp.RequestBody.Required:
+		d.add(ChangeBreaking, "request.body", "request body became required")
+	case oldBody != nil && newBody != nil:
+		d.diffSchema("request.body", *oldBody, *newBody, diffRequest)
+	}
+	oldResponse, newResponse := responseSchema(oldOp), responseSchema(newOp)
+	switch {
+	case oldResponse != nil && newResponse == nil:
+		d.add(ChangeBreaking, "response", "response body removed")
+	case oldResponse != nil && newResponse != nil:
+		d.diffSchema("response", *oldResponse, *newResponse, diffResponse)
+	}
+}
+
+// 请求体优先使用json的schema
+func bodySchema(body *OpenAPIRequestBody) *spec.Schema {
+	if body == nil {
+		return nil
+	}
+	if media, ok := body.Content["application/json"]; ok {
+		return media.Schema
+	}
+	for _, contentType := range sortedKeys(body.Content) {
+		return body.Content[contentType].Schema
+	}
+	return nil
+}
+
+func responseSchema(op *OpenAPIOperation) *spec.Schema {
+	response, ok := op.Responses["200"]
+	if !ok {
+		return nil
+	}
+	if media, ok := response.Content["application/json"]; ok {
+		return media.Schema
+	}
+	for _, contentType := range sortedKeys(response.Content) {
+		return response.Content[contentType].Schema
+	}
+	return nil
+}
+
+// normalizedSchema 解析$ref，合并allOf，去掉null类型；返回schema是否nullable和引用的名字
+func normalizedSchema(schema spec.Schema, doc *OpenAPI) (result spec.Schema, nullable bool, ref string) {
+	// 3.1中nullable的ref为oneOf:[$ref, {type:null}]
+	if len(schema.OneOf) == 2 {
+		for i, item := range schema.OneOf {
+			if item.Type.Contains("null") && len(item.Type) == 1 {
+				nullable = true
+				schema = schema.OneOf[1-i]
+				break
+			}
+		}
+	}
+	if name := strings.TrimPrefix(schema.Ref.String(), componentsPrefix); name != "" {
+		ref = name
+		schema = doc.Components.Schemas[name]
+	}
+	if schema.Type.Contains("null") {
+		nullable = true
+		var types spec.StringOrArray
+		for _, t := range schema.Type {
+			if t != "null" {
+				types = append(types, t)
+			}
+		}
+		schema.Type = types
+	}
+	if len(schema.AllOf) > 0 {
+		merged := spec.Schema{}
+		merged.Description = schema.Description
+		for _, part := range schema.AllOf {
+			part, _, _ = normalizedSchema(part, doc)
+			if len(part.Type) > 0 {
+				merged.Type = part.Type
+			}
+			for _, name := range part.Required {
+				if !slices.Contains(merged.Required, name) {
+					merged.Required = append(merged.Required, name)
+				}
+			}
+			for name, property := range part.Properties {
+				merged.SetProperty(name, property)
+			}
+		}
+		schema = merged
+	}
+	return schema, nullable, ref
+}
+
+func schemaType(schema spec.Schema) string {
+	if len(schema.Type) == 0 {
+		if len(schema.Properties) > 0 {
+			return "object"
+		}
+		return "any"
+	}
+	return schema.Type[0]
+}
+
+// diffSchema 比较请求或响应中的schema；请求收紧和响应放宽都是不兼容的；
+func (d *apiDiffer) diffSchema(location string, oldSchema, newSchema spec.Schema, direction diffDirection) {
+	oldSchema, oldNullable, oldRef := normalizedSchema(oldSchema, d.oldDoc)
+	newSchema, newNullable, newRef := normalizedSchema(newSchema, d.newDoc)
+	if oldRef != "" && newRef != "" {
+		key := fmt.Sprintf("%s|%s|%d|%s", oldRef, newRef, direction, d.endpoint)
+		if d.seen[key] {
+			return
+		}
+		d.seen[key] = true
+	}
+	// 请求中类型变为any，响应中类型从any变为具体类型是兼容的
+	oldType, newType := schemaType(oldSchema), schemaType(newSchema)
+	if oldType != newType {
+		if (direction == diffRequest && newType == "any") || (direction == diffResponse && oldType == "any") {
+			d.add(ChangeNonBreaking, location, "type changed from %s to %s", oldType, newType)
+		} else {
+			d.add(ChangeBreaking, location, "type changed from %s to %s", oldType, newType)
+		}
+		return
+	}
+	if oldSchema.Format != newSchema.Format {
+		d.add(ChangeBreaking, location, "format changed from %q to %q", oldSchema.Format, newSchema.Format)
+	}
+	if direction == diffResponse && newNullable && !oldNullable {
+		d.add(ChangeBreaking, location, "became nullable")
+	}
+	if direction == diffRequest && oldNullable && !newNullable {
+		d.add(ChangeBreaking, location, "no longer accepts null")
+	}
+	d.diffEnum(location, oldSchema.Enum, newSchema.Enum, direction)
+	switch newType {
+	case "object":
+		d.diffProperties(location, oldSchema, newSchema, direction)
+		if oldSchema.AdditionalProperties != nil && oldSchema.AdditionalProperties.Schema != nil &&
+			newSchema.AdditionalProperties != nil && newSchema.AdditionalProperties.Schema != nil {
+			d.diffSchema(location+"{}", *oldSchema.AdditionalProperties.Schema, *newSchema.AdditionalProperties.Schema, direction)
+		}
+	case "array":
+		if oldSchema.Items != nil && oldSchema.Items.Schema != nil && newSchema.Items != nil && newSchema.Items.Schema != nil {
+			d.diffSchema(location+"[]", *oldSchema.Items.Schema, *newSchema.Items.Schema, direction)
+		}
+	}
+}
+
+func (d *apiDiffer) diffEnum(location string, oldEnum, newEnum []any, direction diffDirection) {
+	contains := func(values []any, value any) bool {
+		for _, v := range values {
+			if fmt.Sprint(v) == fmt.Sprint(value) {
+				return true
+			}
+		}
+		return false
+	}
+	var removed, added []string
+	for _, value := range oldEnum {
+		if len(newEnum) > 0 && !contains(newEnum, value) {
+			removed = append(removed, fmt.Sprint(value))
+		}
+	}
+	for _, value := range newEnum {
+		if len(oldEnum) > 0 && !contains(oldEnum, value) {
+			added = append(added, fmt.Sprint(value))
+		}
+	}
+	// 没有enum表示任意值，增加enum为收紧，去掉enum为放宽
+	narrowed := len(removed) > 0 || (len(oldEnum) == 0 && len(newEnum) > 0)
+	widened := len(added) > 0 || (len(oldEnum) > 0 && len(newEnum) == 0)
+	if !narrowed && !widened {
+		return
+	}
+	message := "enum changed"
+	if len(removed) > 0 {
+		message += ", removed " + strings.Join(removed, ",")
+	}
+	if len(added) > 0 {
+		message += ", added " + strings.Join(added, ",")
+	}
+	if (direction == diffRequest && narrowed) || (direction == diffResponse && widened) {
+		d.add(ChangeBreaking, location, "%s", message)
+	} else {
+		d.add(ChangeNonBreaking, location, "%s", message)
+	}
+}
+
+func (d *apiDiffer) diffProperties(location string, oldSchema, newSchema spec.Schema, direction diffDirection) {
+	for _, name := range sortedKeys(newSchema.Properties) {
+		property := location + "." + name
+		oldProperty, ok := oldSchema.Properties[name]
+		newRequired := slices.Contains(newSchema.Required, name)
+		oldRequired := slices.Contains(oldSchema.Required, name)
+		if !ok {
+			if direction == diffRequest && newRequired {
+				d.add(ChangeBreaking, property, "new required field")
+			} else {
+				d.add(ChangeNonBreaking, property, "field added")
+			}
+			continue
+		}
+		switch {
+		case direction == diffRequest && newRequired && !oldRequired:
+			d.add(ChangeBreaking, property, "field became required")
+		case direction == diffResponse && oldRequired && !newRequired:
+			d.add(ChangeBreaking, property, "field is no longer always present")
+		}
+		d.diffSchema(property, oldProperty, newSchema.Properties[name], direction)
+	}
+	for _, name := range sortedKeys(oldSchema.Properties) {
+		if _, ok := newSchema.Properties[name]; ok {
+			continue
+		}
+		if direction == diffResponse {
+			d.add(ChangeBreaking, location+"."+name, "field removed")
+		} else {
+			d.add(ChangeNonBreaking, location+"."+name, "field removed")
+		}
+	}
+}
+
+// WriteText 以文本格式输出比较结果
+func (r *ApiDiffResult) WriteText(w io.Writer) {
+	if len(r.Breaking) == 0 && len(r.NonBreaking) == 0 {
+		fmt.Fprintln(w, "no api changes")
+		return
+	}
+	for _, group := range []struct {
+		title   string
+		changes []ApiChange
+	}{{"Breaking changes", r.Breaking}, {"Non-breaking changes", r.NonBreaking}} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", group.title, len(group.changes))
+		for _, change := range group.changes {
+			location := ""
+			if change.Location != "" {
+				location = " " + change.Location
+			}
+			fmt.Fprintf(w, "  %s%s: %s\n", change.Endpoint, location, change.Message)
+		}
+	}
+}
@@ -20,6 +20,15 @@ type Import struct {
 	Path string
 }
 
+// sortedValues 按照key的顺序返回map的value；生成代码时按照key的顺序遍历map，保证每次生成的结果相同
+func sortedValues[V any](m map[string]V) []V {
+	values := make([]V, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		values = append(values, m[key])
+	}
+	return values
+}
+
 func FirstLower(word string) string {
 	return string(unicode.ToLower([]rune(word)[0])) + word[1:]
 }
@@ -0,0 +1,48 @@
+package astinfo
+
+import (
+	"go/build"
+	"os"
+	"strings"
+)
+
+// buildContext 返回判断文件是否参与编译的build.Context；
+// 文件名后缀(_linux.go，_amd64.go等)和//go:build约束都按照配置的GOOS，GOARCH和Tags计算；
+// 并发加载package时会同时调用，只在第一次调用时创建；
+func (mp *MainProject) buildContext() *build.Context {
+	mp.buildOnce.Do(mp.initBuildContext)
+	return mp.buildCtx
+}
+
+func (mp *MainProject) initBuildContext() {
+	ctxt := build.Default
+	cfg := mp.Cfg.Build
+	if cfg.GOOS != "" {
+		ctxt.GOOS = cfg.GOOS
+	}
+	if cfg.GOARCH != "" {
+		ctxt.GOARCH = cfg.GOARCH
+	}
+	// 交叉编译时与go build一致，默认不开启cgo
+	if ctxt.GOOS != build.Default.GOOS || ctxt.GOARCH != build.Default.GOARCH {
+		ctxt.CgoEnabled = os.Getenv("CGO_ENABLED") == "1"
+	}
+	ctxt.BuildTags = append(ctxt.BuildTags, cfg.Tags...)
+	mp.buildCtx = &ctxt
+}
+
+// buildFlags 返回go/packages加载时使用的环境变量和参数，与buildContext一致
+func (mp *MainProject) buildFlags() (env []string, flags []string) {
+	cfg := mp.Cfg.Build
+	env = os.Environ()
+	if cfg.GOOS != "" {
+		env = append(env, "GOOS="+cfg.GOOS)
+	}
+	if cfg.GOARCH != "" {
+		env = append(env, "GOARCH="+cfg.GOARCH)
+	}
+	if len(cfg.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(cfg.Tags, ","))
+	}
+	return
+}
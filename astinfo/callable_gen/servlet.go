@@ -3,7 +3,6 @@ package callable_gen
 import (
 	"fmt"
 	"log"
-	"os"
 	"path"
 	"strings"
 	"text/template"
@@ -21,6 +20,7 @@ type ServletGen struct {
 	filterMap     map[string]*FilterInfo
 	InternalError int
 	DataError     int
+	commonGened   bool // 多个server使用servlet时，公共代码只生成一次
 }
 
 func NewServletGen(dataError, internalError int) *ServletGen {
@@ -35,8 +35,6 @@ func (servlet *ServletGen) GetName() string {
 	return "servlet"
 }
 
-var commongened bool
-
 // 定义代码生成模板
 const cJsonTemplate = `{{if .HasResponseKey}}
 var responseKey {{.ImportName}}.{{.ResponseKey}}
@@ -96,15 +94,24 @@ func getErrorCode(err error) (int, string) {
 type Coder interface {
 	GetErrorCode() int
 }
+
+// traceFilter 从请求头中读取traceId，tracestate和超时时间，放入请求的context中；
+func traceFilter(c *gin.Context) {
+	ctx, cancel := extractTrace(c.Request.Context(), c.Request.Header)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
 `
 
 func (servlet *ServletGen) GenerateCommon(file *astinfo.GenedFile) {
-	if commongened {
+	if servlet.commonGened {
 		return
 	}
-	commongened = true
+	servlet.commonGened = true
 	var content strings.Builder
 	Project := astinfo.GlobalProject
+	file.GetImport(astinfo.SimplePackage("github.com/gin-gonic/gin", "gin"))
 
 	// 准备模板数据
 	data := struct {
@@ -211,6 +218,8 @@ func (servlet *ServletGen) GenRouterCode(method *astinfo.Method, file *astinfo.G
 		FilterName       string //自带最后一个逗号
 		RequestConstruct string
 		UrlParameterStr  string
+		HeaderBind       bool   // request中有header tag的字段，使用ShouldBindHeader绑定
+		CookieBind       string // request中有cookie tag的字段，从cookie中赋值
 		HasRequest       bool
 		HasResponse      bool
 		ResponseNilCode  string
@@ -226,26 +235,43 @@ func (servlet *ServletGen) GenRouterCode(method *astinfo.Method, file *astinfo.G
 		paramIndex := 1
 		requestParam := method.Params[paramIndex]
 		if !astinfo.IsPointer(requestParam.Type) {
-			fmt.Printf("only pointer is supported in %s of file %s \n", method.Name, method.GoSource.Path)
-			os.Exit(0)
+			astinfo.Errorf(method.Position(), "only pointer request is supported in %s, skip it", method.Name)
+			return name
 		}
 		tm.HasRequest = true
 		tm.RequestConstruct = requestParam.GenVariableCode(file, false)
+		if fields := astinfo.StructFields(astinfo.GetBasicType(requestParam.Type)); fields != nil {
+			tm.HeaderBind = len(astinfo.TaggedFields(fields, "header")) > 0
+			for _, field := range astinfo.TaggedFields(fields, "cookie") {
+				if field.Type.RefName(nil) != "string" {
+					astinfo.Warnf(field.Position(), "only string cookie field is supported, skip %s", field.Name)
+					continue
+				}
+				name, _, _ := strings.Cut(field.Tags["cookie"], ",")
+				tm.CookieBind += fmt.Sprintf("if v, err := c.Cookie(\"%s\"); err == nil {\nrequest.%s = v\n}\n", name, field.Name)
+			}
+		}
 	}
 	if len(method.Results) > 1 {
 		tm.HasResponse = true
 		tm.ResponseNilCode = method.Results[0].GenNilCode(file)
 	}
 
-	//获取可能存在的url中的参数
+	//获取url中的参数，包括receiver上定义的url中的参数，与swagger文档一致
 	methodUrl := strings.Trim(method.Comment.Url, "\"")
-	if strings.Contains(methodUrl, ":") {
-		names := strings.Split(methodUrl, "/")
-		for _, name := range names {
-			if strings.Contains(name, ":") {
-				//此处最好从名字能获取到Field，然后在调用type的parse方法，返回其对应的值；
-				tm.UrlParameterStr += fmt.Sprintf("request.%s=c.Param(\"%s\")\n", astinfo.Capitalize(name[1:]), name[1:])
+	if tm.HasRequest {
+		fields := astinfo.FieldListToMap(astinfo.StructFields(astinfo.GetBasicType(method.Params[1].Type)))
+		for _, segment := range strings.Split(strings.Trim(tm.Url, "\""), "/") {
+			if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+				continue
+			}
+			name := segment[1:]
+			field := fields[astinfo.Capitalize(name)]
+			if field == nil || field.Type.RefName(nil) != "string" {
+				astinfo.Warnf(method.Position(), "url parameter %s of %s should be bound to a string field %s of request, skip it", name, method.Name, astinfo.Capitalize(name))
+				continue
 			}
+			tm.UrlParameterStr += fmt.Sprintf("request.%s=c.Param(\"%s\")\n", field.Name, name)
 		}
 	}
 	userFilters := strings.Split(method.Comment.Filter, ",")
@@ -254,7 +280,7 @@ func (servlet *ServletGen) GenRouterCode(method *astinfo.Method, file *astinfo.G
 		if filter != "" {
 			filterInfo := servlet.filterMap[filter]
 			if filterInfo == nil {
-				fmt.Printf("filter %s not found in file %s for %s \n", filter, method.GoSource.Path, method.Name)
+				astinfo.Errorf(method.Position(), "filter %s not found for %s", filter, method.Name)
 			} else {
 				tm.FilterName += filterInfo.FilterName + ","
 			}
@@ -265,7 +291,7 @@ func (servlet *ServletGen) GenRouterCode(method *astinfo.Method, file *astinfo.G
 			tm.FilterName += filter.FilterName + ","
 		}
 	}
-	tmplText := `engine.{{.HttpMethod}} ( "{{.Url}}", {{.FilterName}} func(c *gin.Context) {
+	tmplText := `engine.{{.HttpMethod}} ( "{{.Url}}", traceFilter, {{.FilterName}} func(c *gin.Context) {
 		{{ if .HasRequest }}
 		request := {{.RequestConstruct}}
 		{{.UrlParameterStr}}	
@@ -277,6 +303,16 @@ func (servlet *ServletGen) GenRouterCode(method *astinfo.Method, file *astinfo.G
 			})
 			return
 		}
+		{{ if .HeaderBind }}
+		if err := c.ShouldBindHeader(request); err != nil {
+			cJSON(c, 200, Response{
+				Code:    {{.DataError}},
+				Message: "param error",
+			})
+			return
+		}
+		{{ end }}
+		{{.CookieBind}}
 		{{ end }}
 		{{ if .HasResponse }}a,{{end}} err := receiver.{{.MethodName}}(c {{ if .HasRequest }},request{{ end }})
 		{{.ResponseNilCode}}
@@ -10,12 +10,13 @@ const TagPrefix = "@gos"
 // const GolangRawType = "rawType"
 
 type Comment interface {
-	dealValuePair(key, value string)
+	// dealValuePair 返回的错误作为warning报告在注释所在的位置
+	dealValuePair(key, value string) error
 	// HasComment() bool
 }
 
 // 注释支持的格式为 @plaso url=xxx ; creator ; filter
-func parseComment(commentGroup *ast.CommentGroup, commentor Comment) {
+func parseComment(commentGroup *ast.CommentGroup, commentor Comment, goSource *Gosourse) {
 	if commentGroup == nil {
 		return
 	}
@@ -23,13 +24,15 @@ func parseComment(commentGroup *ast.CommentGroup, commentor Comment) {
 	for _, comment := range commentGroup.List {
 		text := strings.TrimLeft(comment.Text, "/ \t") // 去掉前面的空格和斜杠
 		if strings.HasPrefix(text, TagPrefix) {
-			parseValidComment(text[len(TagPrefix):], commentor)
+			for _, err := range parseValidComment(text[len(TagPrefix):], commentor) {
+				Warnf(goSource.Position(comment.Pos()), "%s", err.Error())
+			}
 		}
 	}
 }
 
 // 解析有效的comments
-func parseValidComment(validComment string, commentor Comment) {
+func parseValidComment(validComment string, commentor Comment) (errs []error) {
 	commands := Fields(validComment) // 多个参数以;分割
 	for _, command := range commands {
 		command = strings.Trim(command, " \t")
@@ -42,10 +45,29 @@ func parseValidComment(validComment string, commentor Comment) {
 		// 	//去除前后空格和引号
 		// 	valuePair[1] = strings.Trim(valuePair[1], " \t")
 		// }
+		var err error
 		if len(valuePair) == 2 {
-			commentor.dealValuePair(valuePair[0], valuePair[1])
+			err = commentor.dealValuePair(valuePair[0], valuePair[1])
 		} else {
-			commentor.dealValuePair(valuePair[0], "")
+			err = commentor.dealValuePair(valuePair[0], "")
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// docText 返回注释中@gos之外的内容
+func docText(commentGroup *ast.CommentGroup) string {
+	if commentGroup == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(commentGroup.Text(), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), TagPrefix) {
+			lines = append(lines, line)
 		}
 	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
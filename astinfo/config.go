@@ -17,17 +17,85 @@ type Generation struct {
 }
 type Config struct {
 	InitMain   string // 改为字符串类型，存储模块名称
+	Loader     string // 解析方式，ast(默认)或packages；命令行参数-loader优先
+	Cache      string // ast方式的解析缓存目录，相对工程根目录，默认.gos/cache；为"-"时不使用缓存
+	ForceParse bool   `toml:"-"` // 清除缓存，重新解析所有package；由命令行参数-force指定
+	CacheRead  bool   `toml:"-"` // 只读取缓存，不写入；gos check不修改工程中的任何文件
+	NoPublish  bool   `toml:"-"` // 不发布文档，gos check时只比较生成的结果
+	Build      BuildCfg
 	Generation Generation
 	SwaggerCfg SwaggerCfg
 }
+
+// BuildCfg 解析代码的目标平台和build tag，决定哪些文件参与解析，与go build的规则一致
+type BuildCfg struct {
+	GOOS   string   // 默认为当前环境的GOOS
+	GOARCH string   // 默认为当前环境的GOARCH
+	Tags   []string // 额外的build tag，同go build -tags
+}
+
 type SwaggerCfg struct {
+	// ProjectId，ServletFolder，SchemaFolder，Token为旧的apifox配置，Publish.Apifox为空且Token不为空时使用
 	ProjectId     int    // 项目id
 	ServletFolder int    // 生成的servlet文件夹
 	SchemaFolder  int    // 生成的schema文件夹
 	UrlPrefix     string // url前缀, 正式环境和本地的路径不一样
 	Token         string
+	Format        string // 文档格式，swagger2(默认)或openapi3.1
+	Output        string // 文档保存路径，相对工程根目录，默认swagger.json；后缀为.yaml/.yml时保存为yaml
+	DocPath       string // 不为空时，在该路径生成文档页面的路由，如/docs
+	DocUI         string // 文档页面，swagger-ui(默认)或redoc
+	DocAssets     string // 文档页面的js/css所在目录，相对工程根目录；不为空时嵌入到程序中，不使用cdn
+	SchemaName    string // definitions中结构体的命名，package(默认，如biz.HelloRequest)或short(结构体名，重名时使用package)
+	ErrorCatalog  string // 不为空时，将basic.New定义的错误码保存到该文件，后缀为.md时为markdown，否则为json
+	SplitByGroup  bool   // 为每个group额外生成一份文档，如swagger.admin.json；文档路由使用各自group的文档
+	Publish       PublishCfg
+}
+
+// PublishCfg 文档生成后发布的目标，每个目标一个配置段
+type PublishCfg struct {
+	Apifox *ApifoxCfg       // [SwaggerCfg.Publish.Apifox]
+	Http   []HttpPublishCfg // [[SwaggerCfg.Publish.Http]]
+	Dir    []DirPublishCfg  // [[SwaggerCfg.Publish.Dir]]
+}
+
+// RetryCfg 发布失败后的重试策略
+type RetryCfg struct {
+	Retries       int    // 失败后重试的次数，默认2，小于0表示不重试
+	RetryInterval string // 重试间隔，如500ms，默认1s，每次重试翻倍
+}
+
+type ApifoxCfg struct {
+	Url           string // 默认https://api.apifox.com
+	ProjectId     int
+	ServletFolder int
+	SchemaFolder  int
+	Token         string
+	RetryCfg
+}
+
+// HttpPublishCfg 将文档通过http上传到Url
+type HttpPublishCfg struct {
+	Url     string
+	Method  string            // 默认PUT
+	Token   string            // 不为空时，添加Authorization: Bearer Token
+	Headers map[string]string // 其他header
+	RetryCfg
 }
 
+// DirPublishCfg 将文档写入本地目录
+type DirPublishCfg struct {
+	Path     string // 目录，相对工程根目录
+	FileName string // 文件名，默认同Output；后缀为.yaml/.yml时保存为yaml
+	RetryCfg
+}
+
+const (
+	FormatSwagger2  = "swagger2"
+	FormatOpenAPI31 = "openapi3.1"
+	SchemaNameShort = "short"
+)
+
 func (config *Config) Load() {
 	buf, err := os.ReadFile("project.public.toml")
 	if err == nil {
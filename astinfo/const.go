@@ -25,7 +25,11 @@ const (
 	Type        = "type"
 	Group       = "group"
 	AutoGen     = "autogen"
-	Host        = "host" //rpcclient 使用
+	Host        = "host"   //rpcclient 使用
+	Prefix      = "prefix" //rpcclient 变量使用
+	Logger      = "logger" //rpcclient 变量使用
+	Errors      = "errors" //servlet可能返回的错误，如errors=NotFound,Forbidden
+	Tag         = "tag"    //swagger中的tag，struct和servlet上都可以定义
 	//desperate
 	Servlet = "servlet" //用于定义struct是servlet，所以默认groupName是servlets
 	Prpc    = "prpc"    //用于定义struct是prpc，所以默认groupName是prpc
@@ -0,0 +1,217 @@
+package astinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Severity 诊断信息的级别
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+var severityNames = [...]string{"error", "warning", "info"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity 解析-log-level参数，支持error，warning，info
+func ParseSeverity(name string) (Severity, error) {
+	for i, severityName := range severityNames {
+		if name == severityName {
+			return Severity(i), nil
+		}
+	}
+	return SeverityWarning, fmt.Errorf("unknown severity %s, should be one of error, warning, info", name)
+}
+
+// Diagnostic 解析和生成代码过程中发现的问题，位置来自Package.fset；没有位置时Filename为空
+type Diagnostic struct {
+	Severity Severity
+	Position token.Position
+	Message  string
+}
+
+// String 格式与go vet一致，file:line:col: severity: message，方便编辑器和终端跳转
+func (d *Diagnostic) String() string {
+	if d.Position.Filename == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Position, d.Severity, d.Message)
+}
+
+func (d *Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Severity Severity `json:"severity"`
+		File     string   `json:"file,omitempty"`
+		Line     int      `json:"line,omitempty"`
+		Column   int      `json:"column,omitempty"`
+		Message  string   `json:"message"`
+	}{d.Severity, d.Position.Filename, d.Position.Line, d.Position.Column, d.Message})
+}
+
+// DiagnosticCollector 收集所有的诊断信息；
+// 文本格式时，不低于Level的信息在报告时立即输出到Output；JSON格式时在Summary中统一输出；
+type DiagnosticCollector struct {
+	Level  Severity  // 输出的最低级别，默认为warning
+	JSON   bool      // 以JSON数组输出，供编辑器使用
+	Output io.Writer // 诊断信息和Logf的输出，默认为stdout；apidiff和check的stdout用于输出结果，设置为stderr
+
+	lock     sync.Mutex
+	list     []*Diagnostic
+	reported map[Diagnostic]bool // 分组生成swagger等场景会重复检查同一个位置，相同的信息只报告一次
+	count    [len(severityNames)]int
+}
+
+// Diagnostics 全局的诊断信息收集器
+var Diagnostics = &DiagnosticCollector{Level: SeverityWarning}
+
+func (c *DiagnosticCollector) Report(severity Severity, pos token.Position, format string, args ...any) {
+	diagnostic := &Diagnostic{
+		Severity: severity,
+		Position: pos,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.reported[*diagnostic] {
+		return
+	}
+	if c.reported == nil {
+		c.reported = make(map[Diagnostic]bool)
+	}
+	c.reported[*diagnostic] = true
+	c.list = append(c.list, diagnostic)
+	c.count[severity]++
+	if !c.JSON && severity <= c.Level {
+		fmt.Fprintln(c.writer(), diagnostic.String())
+	}
+}
+
+func (c *DiagnosticCollector) writer() io.Writer {
+	if c.Output == nil {
+		return os.Stdout
+	}
+	return c.Output
+}
+
+// Count 返回某个级别的诊断信息数量
+func (c *DiagnosticCollector) Count(severity Severity) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.count[severity]
+}
+
+func (c *DiagnosticCollector) HasErrors() bool {
+	return c.Count(SeverityError) > 0
+}
+
+// List 返回不低于Level的诊断信息，按照位置排序
+func (c *DiagnosticCollector) List() []*Diagnostic {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var list []*Diagnostic
+	for _, diagnostic := range c.list {
+		if diagnostic.Severity <= c.Level {
+			list = append(list, diagnostic)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Position, list[j].Position
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return list
+}
+
+// filter 返回满足条件的诊断信息，按照报告的顺序排列；解析缓存用来保存package解析时的诊断信息
+func (c *DiagnosticCollector) filter(match func(*Diagnostic) bool) []Diagnostic {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var list []Diagnostic
+	for _, diagnostic := range c.list {
+		if match(diagnostic) {
+			list = append(list, *diagnostic)
+		}
+	}
+	return list
+}
+
+// Summary 输出汇总；JSON格式时输出所有不低于Level的诊断信息
+func (c *DiagnosticCollector) Summary(w io.Writer) {
+	if c.JSON {
+		list := c.List()
+		if list == nil {
+			list = []*Diagnostic{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(list)
+		return
+	}
+	errors, warnings := c.Count(SeverityError), c.Count(SeverityWarning)
+	if errors == 0 && warnings == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", errors, warnings)
+}
+
+func Errorf(pos token.Position, format string, args ...any) {
+	Diagnostics.Report(SeverityError, pos, format, args...)
+}
+
+func Warnf(pos token.Position, format string, args ...any) {
+	Diagnostics.Report(SeverityWarning, pos, format, args...)
+}
+
+func Infof(pos token.Position, format string, args ...any) {
+	Diagnostics.Report(SeverityInfo, pos, format, args...)
+}
+
+// Logf 输出生成过程中的进度信息，如文档的保存位置；不是诊断信息，不受Level影响，也不计入汇总
+func Logf(format string, args ...any) {
+	Diagnostics.lock.Lock()
+	defer Diagnostics.lock.Unlock()
+	fmt.Fprintf(Diagnostics.writer(), format, args...)
+}
+
+// Position 返回文件中pos对应的位置；pos无效时只有文件名
+func (g *Gosourse) Position(pos token.Pos) token.Position {
+	if g == nil {
+		return token.Position{}
+	}
+	if pos.IsValid() && g.Pkg != nil && g.Pkg.fset != nil {
+		return g.Pkg.fset.Position(pos)
+	}
+	return token.Position{Filename: g.Path}
+}
+
+// dependSeverity 依赖包中的问题不影响生成结果，只作为info；本工程中的问题作为warning
+func (g *Gosourse) dependSeverity() Severity {
+	if g != nil && g.Pkg != nil && g.Pkg.Simple {
+		return SeverityInfo
+	}
+	return SeverityWarning
+}
@@ -0,0 +1,239 @@
+package astinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// 通过 errorPackage.errorConstructor(code, msg) 定义的全局变量被认为是错误码，如 var NotFound = basic.New(404, "not found")
+const (
+	errorPackage     = "basic"
+	errorConstructor = "New"
+	// swagger中记录每个接口可能返回的错误码
+	errorCodesExtension = "x-error-codes"
+)
+
+// ErrorCode 全局定义的错误变量；code和msg在所有包解析完成后计算，以便引用其他文件中的常量；
+type ErrorCode struct {
+	Name     string
+	Comment  string
+	Pkg      *Package
+	codeExpr ast.Expr
+	msgExpr  ast.Expr
+	code     any // 从解析缓存中读取时没有表达式，使用缓存中计算好的错误码和消息
+	message  string
+}
+
+// errorCodeDoc 错误码在文档中的格式
+type errorCodeDoc struct {
+	Code     any      `json:"code"`
+	Name     string   `json:"name"`
+	Package  string   `json:"package"`
+	Message  string   `json:"message"`
+	Comment  string   `json:"comment,omitempty"`
+	Servlets []string `json:"servlets,omitempty"`
+}
+
+// parseErrorCodes 记录通过basic.New定义的全局变量
+func (g *Gosourse) parseErrorCodes(valueSpec *ast.ValueSpec) {
+	for i, name := range valueSpec.Names {
+		if i >= len(valueSpec.Values) {
+			break
+		}
+		call, ok := valueSpec.Values[i].(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 || !g.isErrorConstructor(call.Fun) {
+			continue
+		}
+		comment := valueSpec.Doc
+		if comment == nil {
+			comment = valueSpec.Comment
+		}
+		g.Pkg.ErrorCodes[name.Name] = &ErrorCode{
+			Name:     name.Name,
+			Comment:  strings.TrimSpace(comment.Text()),
+			Pkg:      g.Pkg,
+			codeExpr: call.Args[0],
+			msgExpr:  call.Args[1],
+		}
+	}
+}
+
+func (g *Gosourse) isErrorConstructor(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name == errorConstructor && g.Pkg.Name == errorPackage
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		return ok && fun.Sel.Name == errorConstructor && path.Base(g.Imports[pkg.Name]) == errorPackage
+	}
+	return false
+}
+
+// value 计算错误码和消息，无法计算时使用源码中的表达式
+func (e *ErrorCode) value() (code any, message string) {
+	if e.codeExpr == nil {
+		return e.code, e.message
+	}
+	codeValue := evalConst(e.codeExpr, 0, e.Pkg.Consts, e.Pkg.constTypes)
+	if codeValue.Kind() == constant.Unknown {
+		code = types.ExprString(e.codeExpr)
+	} else {
+		code = constantValue(codeValue)
+	}
+	msgValue := evalConst(e.msgExpr, 0, e.Pkg.Consts, e.Pkg.constTypes)
+	if msgValue.Kind() == constant.String {
+		message = constant.StringVal(msgValue)
+	} else {
+		message = types.ExprString(e.msgExpr)
+	}
+	return
+}
+
+func (e *ErrorCode) doc() errorCodeDoc {
+	code, message := e.value()
+	return errorCodeDoc{
+		Code:    code,
+		Name:    e.Name,
+		Package: e.Pkg.Module,
+		Message: message,
+		Comment: e.Comment,
+	}
+}
+
+// findErrorCode 查找servlet注释中的错误变量；name为pkg.Name时在名为pkg的包中查找，
+// 否则先在servlet所在的包中查找，再在所有包中查找，找到多个时报错；
+func (mp *MainProject) findErrorCode(name string, pkg *Package) (*ErrorCode, error) {
+	pkgName, varName, qualified := strings.Cut(name, ".")
+	if !qualified {
+		varName = name
+		if errorCode := pkg.ErrorCodes[varName]; errorCode != nil {
+			return errorCode, nil
+		}
+	}
+	var found []*ErrorCode
+	for _, candidate := range sortedValues(mp.Packages) {
+		if qualified && candidate.Name != pkgName {
+			continue
+		}
+		if errorCode := candidate.ErrorCodes[varName]; errorCode != nil {
+			found = append(found, errorCode)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("error code %s is not found", name)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("error code %s is ambiguous, use pkg.%s instead", name, varName)
+}
+
+// addErrorCodes 将servlet注释中的错误码添加到operation和成功响应的描述中
+func (swagger *Swagger) addErrorCodes(servlet *Method, operation *spec.Operation, response *spec.Response, route string) {
+	var docs []errorCodeDoc
+	for _, name := range servlet.Comment.errors {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		errorCode, err := swagger.project.findErrorCode(name, servlet.GoSource.Pkg)
+		if err != nil {
+			Warnf(servlet.Position(), "%s", err.Error())
+			continue
+		}
+		swagger.errorServlets[errorCode] = append(swagger.errorServlets[errorCode], route)
+		docs = append(docs, errorCode.doc())
+	}
+	if len(docs) == 0 {
+		return
+	}
+	operation.AddExtension(errorCodesExtension, docs)
+	var description strings.Builder
+	description.WriteString("code为0表示成功，其他可能的错误码：\n\n| code | name | message |\n| --- | --- | --- |\n")
+	for _, doc := range docs {
+		fmt.Fprintf(&description, "| %v | %s | %s |\n", doc.Code, doc.Name, markdownCell(doc.Message))
+	}
+	response.Description = description.String()
+}
+
+// errorCatalog 返回所有包中定义的错误码，按code和名字排序；Servlets为本文档中引用该错误的接口
+func (swagger *Swagger) errorCatalog() []errorCodeDoc {
+	var docs []errorCodeDoc
+	for _, pkg := range sortedValues(swagger.project.Packages) {
+		for _, errorCode := range sortedValues(pkg.ErrorCodes) {
+			doc := errorCode.doc()
+			doc.Servlets = append([]string(nil), swagger.errorServlets[errorCode]...)
+			sort.Strings(doc.Servlets)
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		ai, aok := a.Code.(int64)
+		bi, bok := b.Code.(int64)
+		switch {
+		case aok && bok && ai != bi:
+			return ai < bi
+		case aok != bok:
+			return aok
+		case !aok && fmt.Sprint(a.Code) != fmt.Sprint(b.Code):
+			return fmt.Sprint(a.Code) < fmt.Sprint(b.Code)
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+	return docs
+}
+
+// saveErrorCatalog 将错误码保存到cfg.ErrorCatalog中，后缀为.md时保存为markdown表格，否则为json
+func (swagger *Swagger) saveErrorCatalog(cfg *SwaggerCfg) error {
+	if cfg.ErrorCatalog == "" {
+		return nil
+	}
+	output := cfg.ErrorCatalog
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(swagger.project.currentProject.Path, output)
+	}
+	docs := swagger.errorCatalog()
+	var content bytes.Buffer
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".md", ".markdown":
+		content.WriteString("# 错误码\n\n| code | name | package | message | comment | servlets |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, doc := range docs {
+			fmt.Fprintf(&content, "| %v | %s | %s | %s | %s | %s |\n", doc.Code, doc.Name, doc.Package,
+				markdownCell(doc.Message), markdownCell(doc.Comment), strings.Join(doc.Servlets, "<br>"))
+		}
+	default:
+		if docs == nil {
+			docs = []errorCodeDoc{}
+		}
+		data, err := json.MarshalIndent(docs, "", "  ")
+		if err != nil {
+			return err
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	if err := GenOutput.WriteFile(output, content.Bytes()); err != nil {
+		return err
+	}
+	logSaved("error catalog", output)
+	return nil
+}
+
+// markdown表格中的内容不能有|和换行
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
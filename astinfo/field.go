@@ -3,6 +3,7 @@ package astinfo
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"strings"
 )
 
@@ -13,7 +14,7 @@ type FieldComment struct {
 	comment     string
 }
 
-func (comment *FieldComment) dealValuePair(key, value string) {
+func (comment *FieldComment) dealValuePair(key, value string) error {
 	switch key {
 	case "default":
 		comment.defaultValue = value
@@ -22,6 +23,7 @@ func (comment *FieldComment) dealValuePair(key, value string) {
 	default:
 		comment.comment = key
 	}
+	return nil
 }
 
 // 变量名和变量类型的定义
@@ -49,7 +51,7 @@ type FieldBasic struct {
 // genVariableCode
 func (f *FieldBasic) GenVariableCode(goGenerated *GenedFile, wire bool) string {
 	if f.Type == nil {
-		fmt.Printf("skip gen variable for field %s as type is nil in %s\n", f.Name, f.GoSource.Path)
+		Errorf(f.Position(), "skip gen variable for field %s as type is nil", f.Name)
 		return ""
 	}
 	variable := Variable{
@@ -60,6 +62,22 @@ func (f *FieldBasic) GenVariableCode(goGenerated *GenedFile, wire bool) string {
 	return variable.Generate(goGenerated)
 }
 
+// Position 返回字段定义的位置
+func (f *FieldBasic) Position() token.Position {
+	return f.GoSource.Position(f.pos())
+}
+
+// pos 字段名的位置，匿名字段为类型的位置
+func (f *FieldBasic) pos() token.Pos {
+	if len(f.astNames) > 0 {
+		return f.astNames[0].Pos()
+	}
+	if f.astType != nil {
+		return f.astType.Pos()
+	}
+	return token.NoPos
+}
+
 func (field *Field) parseTag(fieldType *ast.BasicLit) {
 	if fieldType != nil {
 		tag := fieldType.Value
@@ -81,7 +99,9 @@ func (field *FieldBasic) parseComment(fieldType *ast.CommentGroup) {
 		return
 	}
 	content := strings.Trim(fieldType.List[0].Text, " /")
-	parseValidComment(content, &field.Comment)
+	for _, err := range parseValidComment(content, &field.Comment) {
+		Warnf(field.GoSource.Position(fieldType.Pos()), "%s", err.Error())
+	}
 }
 
 // Parse() error
@@ -105,6 +125,17 @@ func (field *FieldBasic) Parse(typeMap map[string]*Field) error {
 // type TypeC[K]=K;
 // type TypeD[K] interface{***}
 func parseType(fieldType ast.Expr, goSource *Gosourse, typeMap map[string]*Field) Typer {
+	// 数组，指针，map按照ast逐层解析，匿名结构体和interface需要从ast中解析字段的tag和注释；
+	// 其他的类型名从go/types中获取
+	switch fieldType.(type) {
+	case *ast.ArrayType, *ast.StarExpr, *ast.MapType, *ast.StructType, *ast.InterfaceType:
+	default:
+		if info := goSource.Pkg.typesInfo; info != nil && fieldType != nil {
+			if typer, ok := typeOf(fieldType, info, typeMap); ok {
+				return typer
+			}
+		}
+	}
 	var resultType Typer
 	switch fieldType := fieldType.(type) {
 	case *ast.ArrayType:
@@ -116,11 +147,12 @@ func parseType(fieldType ast.Expr, goSource *Gosourse, typeMap map[string]*Field
 		resultType = &array
 		array.Typer = parseType(fieldType.Elt, goSource, typeMap)
 	case *ast.StarExpr:
-		var pointer Typer
-		pointer = parseType(fieldType.X, goSource, typeMap)
-		resultType = NewPointerType(pointer)
+		// 与go/types方式一致，无法解析的类型的指针也为nil
+		if pointer := parseType(fieldType.X, goSource, typeMap); pointer != nil {
+			resultType = NewPointerType(pointer)
+		}
 	case *ast.Ident:
-		resultType = goSource.getType(fieldType.Name, typeMap)
+		resultType = goSource.getType(fieldType.Name, typeMap, fieldType.Pos())
 	case *ast.SelectorExpr:
 		// 其他package的结构体，=》pkg1.Struct
 		// field定义的selector，就只考虑pkg1
@@ -133,34 +165,43 @@ func parseType(fieldType ast.Expr, goSource *Gosourse, typeMap map[string]*Field
 			resultType = GlobalProject.FindPackage(pkgModePath).GetTyper(typeName)
 		}
 	case *ast.MapType:
-		mapType := MapType{}
+		mapType := MapType{
+			BaseType:   BaseType{typeName: "map"},
+			KeyTyper:   parseType(fieldType.Key, goSource, typeMap),
+			ValueTyper: parseType(fieldType.Value, goSource, typeMap),
+		}
 		resultType = &mapType
 	case *ast.InterfaceType:
 		//匿名interface；
+		resultType = NewAnonymousInterface(fieldType, goSource, typeMap)
 	case *ast.StructType:
 		//匿名结构体
+		resultType = NewAnonymousStruct(fieldType, goSource, typeMap)
 	case *ast.IndexListExpr:
 		//var a className[k, v]
 		// ast.IndexListExpr.X=>className;
 		// ast.IndexListExpr.Indices=>[k, v];
-		// className := fieldType.X.(*ast.Ident).Name
-		// goSource.Pkg.FillType(className, &resultType)
+		resultType = newInstanceType(fieldType.X, fieldType.Indices, goSource, typeMap)
 	case *ast.FuncType:
+		resultType = NewFuncType(fieldType, goSource, typeMap)
 	case *ast.ChanType:
+		resultType = &ChanType{
+			Dir:  fieldType.Dir,
+			Elem: parseType(fieldType.Value, goSource, typeMap),
+		}
 	///...号参数在目前的解析情况下不会遇到；
 	case *ast.IndexExpr:
 		//atomic.Pointer[func()]
 		//ast.IndexExpr.X=>atomic.Pointer;
 		//ast.IndexExpr.Index=>func();
-		//fmt.Printf("fieldType is nil in '%s' current not supported\n", goSource.Path)
+		resultType = newInstanceType(fieldType.X, []ast.Expr{fieldType.Index}, goSource, typeMap)
 	case *ast.ParenExpr:
 		//onExit (func(interface{}))
-		//fmt.Printf("fieldType is nil in '%s' current not supported\n", goSource.Path)
+		resultType = parseType(fieldType.X, goSource, typeMap)
 	case nil:
-		fmt.Printf("fieldType is nil in '%s' current not supported\n", goSource.Path)
+		Diagnostics.Report(goSource.dependSeverity(), goSource.Position(token.NoPos), "fieldType is nil, current not supported")
 	default:
-		// TODO: 需要添加日志级别，再打印日志
-		fmt.Printf("unknown field type '%T' in '%s'\n", fieldType, goSource.Path)
+		Diagnostics.Report(goSource.dependSeverity(), goSource.Position(fieldType.Pos()), "unknown field type '%T'", fieldType)
 		return nil
 	}
 	//如果将来Typer需要全局唯一，此处可以先找到唯一值，再赋值给typer；
@@ -181,26 +222,23 @@ func (field *Field) Parse(typeMap map[string]*Field) error {
 	field.parseTag(field.astTag)
 	return field.FieldBasic.Parse(typeMap)
 }
+
+// GenNilCode 生成将字段(变量名为a)中为nil的数组初始化为空数组的代码，没有需要初始化的数组时返回空字符串
 func (field *Field) GenNilCode(file *GenedFile) string {
-	nt := field.Type
-	if IsPointer(field.Type) {
-		nt = GetBasicType(nt)
-		res := "if a!=nil {\n" + field.genNilCode(nt, file) + "\n}\n"
-		return res
-	}
-	return field.genNilCode(nt, file)
+	return field.genNilCode(file, make(map[string]bool))
 }
 
-func (field *Field) genNilCode(nt Typer, file *GenedFile) string {
-	switch nt := nt.(type) {
-	case *Struct:
-		return nt.GenNilCode(file)
-	case *ArrayType:
-		name := field.Type.RefName(file)
-		return fmt.Sprintf("if *a == nil {\n*a = %s{}\n}", name)
-	default:
-		return ""
+// genNilCode visiting为正在展开的结构体，避免自引用的结构体无限递归
+func (field *Field) genNilCode(file *GenedFile, visiting map[string]bool) string {
+	nt := field.Type
+	if IsPointer(nt) {
+		code := genTypeNilCode(GetBasicType(nt), file, visiting)
+		if code == "" {
+			return ""
+		}
+		return "if a!=nil {\n" + code + "\n}\n"
 	}
+	return genTypeNilCode(nt, file, visiting)
 }
 
 func NewField(root *ast.Field, source *Gosourse) *Field {
@@ -231,7 +269,33 @@ type VarFieldHelper struct {
 	goSource *Gosourse
 }
 
-type VarField = FieldBasic
+// 全局变量上的注释，目前仅用于rpc client变量；
+// @gos host=xxx; prefix=xxx; logger=xxx
+// host，prefix，logger的值可以是字符串常量，也可以是变量所在包中的全局变量名；
+type varComment struct {
+	Host   string // 覆盖interface上定义的host
+	Prefix string // 追加在host之后的url前缀
+	Logger string // 该client使用的rpcLogger变量
+}
+
+func (comment *varComment) dealValuePair(key, value string) error {
+	switch key {
+	case Host:
+		comment.Host = value
+	case Prefix:
+		comment.Prefix = value
+	case Logger:
+		comment.Logger = value
+	default:
+		return fmt.Errorf("unknown key value pair => key=%s,value=%s", key, value)
+	}
+	return nil
+}
+
+type VarField struct {
+	FieldBasic
+	VarComment varComment
+}
 
 func NewVarFieldHelper(root *ast.ValueSpec, source *Gosourse) *VarFieldHelper {
 	return &VarFieldHelper{
@@ -255,9 +319,14 @@ func (v *VarFieldHelper) Parse() error {
 		return nil
 	}
 	field.Parse(nil)
+	var comment varComment
+	parseComment(root.Doc, &comment, v.goSource)
 	if len(root.Names) != 0 {
 		for _, name := range root.Names {
-			field1 := field
+			field1 := VarField{
+				FieldBasic: field,
+				VarComment: comment,
+			}
 			field1.Name = name.Name
 			v.goSource.Pkg.GlobalVar[name.Name] = &field1
 		}
@@ -3,6 +3,7 @@ package astinfo
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"strings"
 )
 
@@ -13,6 +14,8 @@ type functionComment struct {
 	isDeprecated bool
 	funcType     string //函数类型，filter，servlet，websocket，prpc，initiator,creator
 	security     []string
+	errors       []string // 可能返回的错误变量名，可以为pkg.Name的形式
+	tag          string   // swagger中的tag，默认为receiver的tag
 	groupName    string
 	Filter       string
 	owner        *Function
@@ -44,7 +47,7 @@ type Function struct {
 	FunctionField
 }
 
-func (comment *functionComment) dealValuePair(key, value string) {
+func (comment *functionComment) dealValuePair(key, value string) error {
 	key = strings.ToLower(key)
 	value = strings.Trim(value, "\"")
 	switch key {
@@ -59,10 +62,14 @@ func (comment *functionComment) dealValuePair(key, value string) {
 		}
 	case Security:
 		comment.security = strings.Split(value, ",")
+	case Errors:
+		comment.errors = strings.Split(value, ",")
+	case Tag:
+		comment.tag = value
 	case ConstMethod:
 		comment.Method = strings.ToUpper(value)
 		if _, ok := methodMap[comment.Method]; !ok {
-			fmt.Printf("method '%s' is not supported in function comment %s in %s\n", comment.Method, comment.owner.Name, comment.owner.GoSource.Path)
+			return fmt.Errorf("method '%s' is not supported in function comment %s", comment.Method, comment.owner.Name)
 		}
 	case Title:
 		comment.title = value
@@ -80,9 +87,10 @@ func (comment *functionComment) dealValuePair(key, value string) {
 		comment.Filter = value
 	default:
 		if !comment.dealOldValuePair(key, value) {
-			fmt.Printf("unknown key '%s' in function comment %s in %s\n", key, comment.owner.Name, comment.owner.GoSource.Path)
+			return fmt.Errorf("unknown key '%s' in function comment %s", key, comment.owner.Name)
 		}
 	}
+	return nil
 }
 func (comment *functionComment) dealOldValuePair(key, value string) bool {
 	switch key {
@@ -123,6 +131,11 @@ func NewFunction(funcDecl *ast.FuncDecl, goSource *Gosourse) *Function {
 	return fun
 }
 
+// Position 返回函数定义的位置
+func (f *Function) Position() token.Position {
+	return f.GoSource.Position(f.funcDecl.Pos())
+}
+
 // GetType() string
 func (f *Function) GetType() string {
 	return f.Comment.funcType
@@ -130,11 +143,11 @@ func (f *Function) GetType() string {
 
 // 解析自己，并把自己添加到对应的functionManager中；
 func (f *Function) Parse() error {
-	parseComment(f.funcDecl.Doc, &f.Comment)
 	f.Name = f.funcDecl.Name.Name
+	parseComment(f.funcDecl.Doc, &f.Comment, f.GoSource)
 	//没有类型的函数，不解析；
 	if f.Comment.funcType != "" {
-		f.parseParameter(f.funcDecl.Type)
+		f.parseParameter(f.funcDecl.Type, nil)
 	}
 	return nil
 }
@@ -20,12 +20,13 @@ func (f *FunctionField) GeneredFields() []*Field {
 	return f.Results
 }
 
-func (f *FunctionField) parseParameter(paramType *ast.FuncType) bool {
+// typeMap为外层的范型参数，如匿名interface所在结构体的范型参数
+func (f *FunctionField) parseParameter(paramType *ast.FuncType, typeMap map[string]*Field) bool {
 	//Params参数不可能为nil
-	f.Params = parseFields(paramType.Params.List, f.GoSource, nil)
+	f.Params = parseFields(paramType.Params.List, f.GoSource, typeMap)
 	//Results返回值可能为nil
 	if paramType.Results != nil {
-		f.Results = parseFields(paramType.Results.List, f.GoSource, nil)
+		f.Results = parseFields(paramType.Results.List, f.GoSource, typeMap)
 	}
 	return true
 }
@@ -1,9 +1,8 @@
 package astinfo
 
 import (
-	"fmt"
 	"go/format"
-	"os"
+	"go/token"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -13,6 +12,8 @@ import (
 type GenedFile struct {
 	pkg *Package
 	// for gen code
+	pkgName              string             //生成代码的包名，默认为gen
+	dir                  string             //相对gen目录的子目录，为空表示gen目录
 	name                 string             //文件名,没有go后缀
 	genCodeImport        map[string]*Import //产生code时会引入其他模块的内容，此时每个模块需要一个名字；但是名字还不能重复
 	genCodeImportNameMap map[string]int     //记录mode的个数；
@@ -22,6 +23,7 @@ type GenedFile struct {
 
 func createGenedFile(fileName string) *GenedFile {
 	return &GenedFile{
+		pkgName:              "gen",
 		name:                 fileName,
 		genCodeImport:        make(map[string]*Import),
 		genCodeImportNameMap: make(map[string]int),
@@ -29,6 +31,14 @@ func createGenedFile(fileName string) *GenedFile {
 	}
 }
 
+// createSubGenedFile 生成gen子目录中的文件，如gen/mocks，包名为子目录名；
+func createSubGenedFile(dir, fileName string) *GenedFile {
+	file := createGenedFile(fileName)
+	file.pkgName = filepath.Base(dir)
+	file.dir = dir
+	return file
+}
+
 // 保存文件
 // 生成package语句
 // 生成import语句
@@ -38,7 +48,7 @@ func (file *GenedFile) save() {
 		return
 	}
 	content := strings.Builder{}
-	content.WriteString("package gen\n")
+	content.WriteString("package " + file.pkgName + "\n")
 	content.WriteString(file.genImportCode())
 	for _, content1 := range file.contents {
 		content.WriteString(content1.String())
@@ -46,15 +56,14 @@ func (file *GenedFile) save() {
 	src := []byte(content.String())
 	src1, err := format.Source(src)
 	if err != nil {
-		fmt.Printf("find err in %s: %s\n", file.name, err.Error())
+		Errorf(token.Position{Filename: file.name}, "format generated code failed: %s", err.Error())
 	} else {
 		src = src1
 	}
-	osfile, err := os.Create(file.name + ".go")
-	if err != nil {
-		panic(err)
+	name := filepath.Join(GlobalProject.genDir(), file.dir, file.name+".go")
+	if err := GenOutput.WriteFile(name, src); err != nil {
+		Errorf(token.Position{Filename: name}, "save generated code failed: %s", err.Error())
 	}
-	osfile.Write(src)
 }
 
 func (file *GenedFile) AddBuilder(builder *strings.Builder) {
@@ -101,7 +110,7 @@ func (file *GenedFile) genImportCode() string {
 	sb.WriteString("import (\n")
 	imports := make([]string, len(file.genCodeImport))
 	var i = 0
-	for _, v := range file.genCodeImport {
+	for _, v := range sortedValues(file.genCodeImport) {
 		// baseName := filepath.Base(v.Path)
 		imports[i] = v.Name + " \"" + v.Path + "\""
 		/*
@@ -1,7 +1,6 @@
 package astinfo
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"strings"
@@ -36,11 +35,21 @@ func (g *Gosourse) parseType(typeSpec *ast.TypeSpec) {
 func (g *Gosourse) getGenDeclParser(genDecl *ast.GenDecl) (parser Parser) {
 	switch genDecl.Tok {
 	case token.VAR:
+		//同type，单个var定义时，注释在genDecl.Doc中；
+		if len(genDecl.Specs) == 1 {
+			valueSpec := genDecl.Specs[0].(*ast.ValueSpec)
+			if valueSpec.Doc == nil {
+				valueSpec.Doc = genDecl.Doc
+			}
+		}
 		for _, spec := range genDecl.Specs {
 			typeSpec := spec.(*ast.ValueSpec)
 			parser = NewVarFieldHelper(typeSpec, g)
 			g.Pkg.AddParser(parser)
+			g.parseErrorCodes(typeSpec)
 		}
+	case token.CONST:
+		g.parseConstDecl(genDecl)
 	case token.TYPE:
 		//如果是一个Specs，那么是一个type定义模式；则将其送到Specs[0].Doc中；
 		if len(genDecl.Specs) == 1 {
@@ -62,7 +71,7 @@ func (g *Gosourse) getGenDeclParser(genDecl *ast.GenDecl) (parser Parser) {
 // field.Type =
 // 先检查原始类型；
 // 来自 import . "****"
-func (g *Gosourse) getType(typeName string, typeMap map[string]*Field) Typer {
+func (g *Gosourse) getType(typeName string, typeMap map[string]*Field, pos token.Pos) Typer {
 	//check raw type
 	type1 := GetRawType(typeName)
 	if type1 != nil {
@@ -87,7 +96,7 @@ func (g *Gosourse) getType(typeName string, typeMap map[string]*Field) Typer {
 			return type3
 		}
 	}
-	fmt.Printf("failed to find type %s in %s\n", typeName, g.Path)
+	Diagnostics.Report(g.dependSeverity(), g.Position(pos), "failed to find type %s", typeName)
 	return nil
 }
 
@@ -122,17 +131,6 @@ func (g *Gosourse) getFuncDeclParser(funcDecl *ast.FuncDecl) Parser {
 //		}
 //		return true
 //	}
-func isIgnoreFile(file *ast.File) bool {
-	for _, c := range file.Comments {
-		for _, line := range c.List {
-			if strings.Contains(line.Text, "//go:build ignore") ||
-				strings.Contains(line.Text, "// +build ignore") {
-				return true
-			}
-		}
-	}
-	return false
-}
 func (g *Gosourse) Parse() error {
 	g.parseImport(g.File.Imports)
 	decls := g.File.Decls
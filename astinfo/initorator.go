@@ -2,6 +2,7 @@ package astinfo
 
 import (
 	"fmt"
+	"go/token"
 	"strconv"
 	"strings"
 	"text/template"
@@ -52,7 +53,7 @@ func (g *InitGroup) addNode(node *DependNode) {
 	} else if node.getReturnName() == "" {
 		if g.Default.getReturnName() == "" {
 			// 这里无法获取函数名，暂时注释掉
-			fmt.Printf("more than one function return the same type %s,but without name\n", g.Default.getReturnField().Type.IDName())
+			Warnf(token.Position{}, "more than one function return the same type %s, but without name", g.Default.getReturnField().Type.IDName())
 		} else {
 			g.Default = node
 		}
@@ -134,7 +135,7 @@ func PrepareTest() {
 		panic(err)
 	}
 	var typeValue []string
-	for _, v := range im.variableMap {
+	for _, v := range sortedValues(im.variableMap) {
 		typeValue = append(typeValue, v.Default.returnVariableName)
 	}
 	err = tmpl.Execute(&testCode, struct {
@@ -166,12 +167,17 @@ func (im *InitManager) collect() ([]*DependNode, VariableMap) {
 	var waittingVariableMap VariableMap = make(map[string]*InitGroup)
 	// 收集initiator到functions中；
 	// 建立候选变量map
-	for _, pkg := range p.Packages {
+	for _, pkg := range sortedValues(p.Packages) {
 		for _, function := range pkg.Initiator {
+			// 返回值的类型无法解析时（如依赖的模块不存在），无法生成变量，也无法按照类型注入
+			if len(function.Results) > 0 && function.Results[0].Type == nil {
+				Errorf(function.Position(), "can't resolve return type of initiator %s", function.Name)
+				continue
+			}
 			node := waittingVariableMap.addVGenerator(function)
 			dependNode = append(dependNode, node)
 		}
-		for _, class := range pkg.Structs {
+		for _, class := range sortedValues(pkg.Structs) {
 			if class.Comment.AutoGen {
 				node := waittingVariableMap.addVGenerator(class)
 				dependNode = append(dependNode, node)
@@ -229,7 +235,7 @@ func (im *InitManager) initParent(node *DependNode, waittingVariableMap Variable
 		if parent != nil {
 			node.Parent = append(node.Parent, parent)
 		} else {
-			fmt.Printf("can't init field: %s not found for type %s\n", param.Name, param.Type.IDName())
+			Errorf(param.Position(), "can't init field: %s not found for type %s", param.Name, param.Type.IDName())
 		}
 	}
 }
@@ -9,13 +9,14 @@ type InterfaceFieldComment struct {
 	Url string
 }
 
-func (comment *InterfaceFieldComment) dealValuePair(key, value string) {
+func (comment *InterfaceFieldComment) dealValuePair(key, value string) error {
 	switch key {
 	case Url:
 		comment.Url = value
 	default:
-		fmt.Printf("unkonw key value pair => key=%s,value=%s\n", key, value)
+		return fmt.Errorf("unknown key value pair => key=%s,value=%s", key, value)
 	}
+	return nil
 }
 
 type InterfaceField struct {
@@ -35,9 +36,13 @@ func NewInterfaceField(field *ast.Field, goSource *Gosourse) *InterfaceField {
 
 // Parse 解析接口字段
 func (f *InterfaceField) Parse() error {
+	return f.parse(nil)
+}
+
+func (f *InterfaceField) parse(typeMap map[string]*Field) error {
 	// 解析字段名称
-	parseComment(f.astRoot.Doc, &f.Comment)
+	parseComment(f.astRoot.Doc, &f.Comment, f.GoSource)
 	f.Name = f.astRoot.Names[0].Name
-	f.parseParameter(f.astRoot.Type.(*ast.FuncType))
+	f.parseParameter(f.astRoot.Type.(*ast.FuncType), typeMap)
 	return nil
 }
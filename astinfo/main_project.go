@@ -2,13 +2,21 @@ package astinfo
 
 import (
 	"fmt"
+	"go/build"
+	"go/token"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 )
 
 type MainProject struct {
@@ -20,13 +28,18 @@ type MainProject struct {
 	InitFuncs4All    []string   // 启动服务器和启动test都是用的方法；
 	InitFuncs4Server []string   // 启动服务器用的方法；
 	Projects         []*Project // 项目包含的子项目集合（key为Project的module）
+	buildCtx         *build.Context
+	buildOnce        sync.Once
+	parseCache       *parseCache   // ast方式解析时，package的解析缓存
+	lock             sync.Mutex    // 保护Packages，package的文件是并发加载的
+	loadLimit        chan struct{} // 同时加载package的数量，默认为GOMAXPROCS
 }
 
 func (mp *MainProject) genGoMod() {
 	_, err := os.Stat("go.mod")
 	if os.IsNotExist(err) {
 		var content = "module " + mp.Cfg.InitMain + "\n" + strings.Replace(runtime.Version(), "go", "go ", 1) + "\n"
-		os.WriteFile("go.mod", []byte(content), 0660)
+		GenOutput.WriteFile(filepath.Join(mp.currentProject.Path, "go.mod"), []byte(content))
 	}
 }
 
@@ -53,14 +66,13 @@ func run() {
 	wg.Wait()
 }
 	`)
-	os.WriteFile("main.go", []byte(content.String()), 0660)
+	GenOutput.WriteFile(filepath.Join(mp.currentProject.Path, "main.go"), []byte(content.String()))
 
 }
 
 // genBasic 生成basic.go
 func (mp *MainProject) genBasic() {
-	os.Mkdir("basic", 0750)
-	os.WriteFile("basic/message.go", []byte(`package basic
+	GenOutput.WriteFile(filepath.Join(mp.currentProject.Path, "basic", "message.go"), []byte(`package basic
 type Error struct {
 	Code    int    "json:\"code\""
 	Message string "json:\"message\""
@@ -79,18 +91,19 @@ func New(code int, msg string) error {
 func (error *Error) GetErrorCode() int {
 	return error.Code
 }
-	`), 0660)
+	`))
+}
+
+// genDir 生成代码的gen目录
+func (mp *MainProject) genDir() string {
+	return filepath.Join(mp.currentProject.Path, "gen")
 }
 
 func (mp *MainProject) genProjectCode() {
-	err := os.Mkdir("gen", 0750)
-	if err != nil && !os.IsExist(err) {
-		log.Fatal(err)
-	}
 	file := createGenedFile("goservlet_project")
 	file.GetImport(SimplePackage("github.com/gin-gonic/gin", "gin"))
-	os.Chdir("gen")
 	mp.genBasicCode(file)
+	mp.genTraceCode(file)
 	mp.genPrepare(file)
 	file.save()
 }
@@ -197,6 +210,122 @@ var servers map[string]*server
 	file.AddBuilder(&content)
 }
 
+// genTraceCode 生成trace相关的公共代码，供servlet读取和rpc client传递traceId，tracestate和超时时间；
+// 支持w3c的traceparent，tracestate格式，同时保留原有的TraceId头；
+func (mp *MainProject) genTraceCode(file *GenedFile) {
+	for _, pkg := range []string{"context", "crypto/rand", "crypto/sha256", "encoding/hex", "net/http", "strconv", "strings", "time"} {
+		file.GetImport(SimplePackage(pkg, path.Base(pkg)))
+	}
+	var content strings.Builder
+	key := mp.Cfg.Generation.TraceKey
+	if key != "" {
+		oneImport := file.GetImport(SimplePackage(mp.Cfg.Generation.TraceKeyMod, "xx"))
+		content.WriteString(fmt.Sprintf("var TraceIdNameInContext = %s.%s{}\n", oneImport.Name, key))
+	} else {
+		content.WriteString(`
+// 没有配置Generation.TraceKey时使用的key
+type traceIdKey struct{}
+
+var TraceIdNameInContext = traceIdKey{}
+`)
+	}
+	content.WriteString(`
+const (
+	TraceParent    = "traceparent"
+	TraceState     = "tracestate"
+	RequestTimeout = "X-Request-Timeout" // 调用方剩余的超时时间，单位毫秒
+)
+
+type traceStateKey struct{}
+
+// GetTraceId 获取ctx中的traceId，不存在时返回空字符串
+func GetTraceId(ctx context.Context) string {
+	traceId, _ := ctx.Value(TraceIdNameInContext).(string)
+	return traceId
+}
+
+func newTraceId() string {
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+// w3c的trace-id必须是32位小写16进制，traceId不满足时，用其hash值代替，保证同一个traceId得到相同的trace-id
+func w3cTraceId(traceId string) string {
+	if isHex(traceId, 32) {
+		return traceId
+	}
+	sum := sha256.Sum256([]byte(traceId))
+	return hex.EncodeToString(sum[:16])
+}
+
+// parseTraceParent 解析 version-traceid-parentid-flags 格式的traceparent，返回trace-id
+func parseTraceParent(traceParent string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isHex(parts[1], 32) || !isHex(parts[2], 16) {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// injectTrace 向请求头写入traceId，traceparent，tracestate和剩余超时时间；ctx中没有traceId时生成新的traceId
+func injectTrace(ctx context.Context, header http.Header) {
+	traceId := GetTraceId(ctx)
+	if traceId == "" {
+		traceId = newTraceId()
+	}
+	header.Set(TraceId, traceId)
+	header.Set(TraceParent, "00-"+w3cTraceId(traceId)+"-"+randomHex(8)+"-01")
+	if state, ok := ctx.Value(traceStateKey{}).(string); ok && state != "" {
+		header.Set(TraceState, state)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline).Milliseconds()
+		if timeout < 1 {
+			timeout = 1
+		}
+		header.Set(RequestTimeout, strconv.FormatInt(timeout, 10))
+	}
+}
+
+// extractTrace 从请求头中读取traceId，tracestate和超时时间，放入ctx中；
+// traceId依次从ctx，TraceId头，traceparent头中获取，都没有则生成新的traceId
+func extractTrace(ctx context.Context, header http.Header) (context.Context, context.CancelFunc) {
+	traceId := GetTraceId(ctx)
+	if traceId == "" {
+		traceId = header.Get(TraceId)
+	}
+	if traceId == "" {
+		traceId, _ = parseTraceParent(header.Get(TraceParent))
+	}
+	if traceId == "" {
+		traceId = newTraceId()
+	}
+	ctx = context.WithValue(ctx, TraceIdNameInContext, traceId)
+	if state := header.Get(TraceState); state != "" {
+		ctx = context.WithValue(ctx, traceStateKey{}, state)
+	}
+	if timeout, err := strconv.ParseInt(header.Get(RequestTimeout), 10, 64); err == nil && timeout > 0 {
+		return context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+	}
+	return ctx, func() {}
+}
+`)
+	file.AddBuilder(&content)
+}
+
 type Server struct {
 	Name             string
 	callGen          CallableGen
@@ -240,7 +369,7 @@ func (sm *Server) Generate(file *GenedFile) {
 func (sm *Server) generateBegin(class *Struct, file *GenedFile) string {
 	var name = strings.Join([]string{
 		"init",
-		class.Comment.GroupName,
+		identifierSuffix(class.Comment.GroupName),
 		class.goSource.Pkg.Name,
 		class.StructName,
 		"router",
@@ -292,9 +421,9 @@ func (sm *ServerManager) Prepare() {
 // 扫描所有的程序，将服务按照group分为多个server；
 func (sm *ServerManager) splitServers() {
 	project := GlobalProject
-	for _, pkg := range project.Packages {
+	for _, pkg := range sortedValues(project.Packages) {
 		// 结构体会定义group和type，所以先扫描struct
-		for _, router := range pkg.Structs {
+		for _, router := range sortedValues(pkg.Structs) {
 			var server *Server
 			var ok bool
 			var groupName = router.Comment.GroupName
@@ -304,7 +433,7 @@ func (sm *ServerManager) splitServers() {
 			if server, ok = sm.servers[groupName]; !ok {
 				gen := sm.generator[router.Comment.serverType]
 				if gen == nil {
-					fmt.Printf("failed to found generator %s\n", router.Comment.serverType)
+					Errorf(router.goSource.Position(router.astRoot.Pos()), "failed to found generator %s", router.Comment.serverType)
 					continue
 				}
 				server = &Server{
@@ -316,7 +445,7 @@ func (sm *ServerManager) splitServers() {
 			server.routers = append(server.routers, router)
 		}
 	}
-	for _, pkg := range project.Packages {
+	for _, pkg := range sortedValues(project.Packages) {
 		for _, filter := range pkg.Filter {
 			var server *Server
 			var ok bool
@@ -324,7 +453,7 @@ func (sm *ServerManager) splitServers() {
 			if server, ok = sm.servers[groupName]; ok {
 				server.filters = append(server.filters, filter)
 			} else {
-				fmt.Printf("failed to found server %s\n", groupName)
+				Errorf(filter.Position(), "failed to found server %s", groupName)
 			}
 		}
 	}
@@ -335,7 +464,7 @@ func (sm *ServerManager) Generate(file *GenedFile) {
 	// if len(sm.servers) == 0 {
 	// 	return
 	// }
-	for _, server := range sm.servers {
+	for _, server := range sortedValues(sm.servers) {
 		//一个server一个文件；
 		file1 := createGenedFile(server.Name)
 		server.Generate(file1)
@@ -374,7 +503,19 @@ func initServer(){
 		RouterNames string
 	}
 	var s []*ServerInfo
-	for _, server := range sm.servers {
+	// 文档路由注册到每个server中，SplitByGroup时每个server使用自己group的文档
+	if docRouters := genDocRouterCode(&GlobalProject.Cfg.SwaggerCfg); docRouters != nil {
+		for _, server := range sortedValues(sm.servers) {
+			docRouter, ok := docRouters[""]
+			if !ok {
+				docRouter, ok = docRouters[server.Name]
+			}
+			if ok {
+				server.GenerateRouters = append(server.GenerateRouters, docRouter)
+			}
+		}
+	}
+	for _, server := range sortedValues(sm.servers) {
 		server := &ServerInfo{
 			Name:        server.Name,
 			FilterNames: strings.Join(server.GeneratedFilters, ",\n"),
@@ -393,38 +534,64 @@ func initServer(){
 
 // GetPackage retrieves a package by module path without creation
 func (mp *MainProject) GetPackage(module string) *Package {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
 	return mp.Packages[module]
 }
 
 // FindPackage finds or creates a package with automatic module path resolution
+// 可以在多个goroutine中调用，同一个package只创建和加载一次
 func (mp *MainProject) FindPackage(module string) *Package {
-	if pkg := mp.GetPackage(module); pkg != nil {
-		return pkg
-	}
 	if module == mp.currentProject.Module+"/gen" {
 		newPkg := NewPackage(module, true, path.Join(mp.currentProject.Path, "gen"))
 		newPkg.finshedParse = true
 		newPkg.Name = "gen"
 		return newPkg
 	}
+	mp.lock.Lock()
+	pkg := mp.Packages[module]
+	if pkg == nil {
+		pkg = mp.newPackage(module)
+		mp.Packages[module] = pkg
+	}
+	mp.lock.Unlock()
+	pkg.load()
+	return pkg
+}
 
+func (mp *MainProject) newPackage(module string) *Package {
 	for _, p := range mp.Projects {
 		// 根据module寻找package
 		if p.Module == "" {
 			panic(fmt.Sprintf("project module is empty %s\n", p.Path))
 		}
 		if strings.HasPrefix(module, p.Module) {
-			newPkg := NewPackage(module, p.Simple, path.Join(p.Path, module[len(p.Module):]))
-			mp.Packages[module] = newPkg
-			newPkg.SimpleParse()
-			return newPkg
+			return NewPackage(module, p.Simple, path.Join(p.Path, module[len(p.Module):]))
 		}
 	}
-	newPkg := NewSysPackage(module)
-	newPkg.SimpleParse()
-	mp.Packages[module] = newPkg
 	//此处识别为系统Package
-	return newPkg
+	return NewSysPackage(module)
+}
+
+// prefetch 并发加载package的文件；解析语法树占用了大部分的时间，且各个package之间互不依赖；
+// 类型的解析仍然由调用者按照原来的顺序进行，保证生成的结果与顺序加载一致；
+func (mp *MainProject) prefetch(modules []string) {
+	var wg sync.WaitGroup
+	for _, module := range modules {
+		if module == "C" {
+			continue
+		}
+		mp.loadLimit <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-mp.loadLimit
+				wg.Done()
+			}()
+			mp.FindPackage(module)
+		}()
+	}
+	wg.Wait()
 }
 
 // GenerateCode 生成项目的代码
@@ -443,20 +610,7 @@ func (mp *MainProject) GenerateCode() error {
 	}
 	mp.genProjectCode()
 
-	NewSwagger(mp).GenerateCode(&mp.Cfg.SwaggerCfg)
-	return nil
-}
-func escapeModulePath(s string) string {
-	var result strings.Builder
-	for _, r := range s {
-		if r >= 'A' && r <= 'Z' {
-			result.WriteRune('!')
-			result.WriteRune(r - 'A' + 'a') // 转为小写
-		} else {
-			result.WriteRune(r)
-		}
-	}
-	return result.String()
+	return NewSwagger(mp).GenerateCode(&mp.Cfg.SwaggerCfg)
 }
 
 // Parse 解析项目的代码
@@ -480,21 +634,13 @@ func (mp *MainProject) Parse() error {
 		cfg.Generation.ResponseMod = p.Module + "/" + responseMod
 	}
 	mp.Projects = append(mp.Projects, p)
-	goPath := os.Getenv("GOPATH")
-	for _, mod := range p.Require {
-		// if mod.Indirect {
-		// 	continue
-		// }
-
-		p := Project{
-			Path:   path.Join(goPath, "pkg/mod", escapeModulePath(mod.Mod.Path)+"@"+mod.Mod.Version),
-			Simple: true,
+	mp.addDependencies(p)
+	if cfg.Loader == LoaderPackages {
+		if err := mp.loadPackages(p); err != nil {
+			return err
 		}
-		p.ParseModule()
-		if p.Module == "" {
-			p.Module = mod.Mod.Path
-		}
-		mp.Projects = append(mp.Projects, &p)
+	} else {
+		mp.parseCache = newParseCache(p.Path, cfg.Cache, cfg.ForceParse, cfg.CacheRead, mp.buildContext())
 	}
 	sort.Slice(mp.Projects, func(i, j int) bool {
 		return mp.Projects[i].Module > mp.Projects[j].Module
@@ -502,12 +648,105 @@ func (mp *MainProject) Parse() error {
 	return p.ParseCode()
 }
 
+// addDependencies 添加依赖的模块；使用go.work时，workspace中的其他模块优先于require的模块；
+// workspace中各模块的require和replace都生效，同一个模块被多次require时使用最高的版本，与go的MVS结果一致；
+func (mp *MainProject) addDependencies(p *Project) {
+	resolver := &moduleResolver{}
+	modules := make(map[string]bool)
+	// 需要添加的require，key为模块路径；goMod为第一次require该模块的go.mod，用于报告错误
+	type requirement struct {
+		mod   module.Version
+		goMod string
+	}
+	requires := make(map[string]*requirement)
+	var requireOrder []string
+	addRequires := func(dir string, list []*modfile.Require) {
+		for _, req := range list {
+			if old, ok := requires[req.Mod.Path]; ok {
+				if semver.Compare(req.Mod.Version, old.mod.Version) > 0 {
+					old.mod = req.Mod
+				}
+				continue
+			}
+			requires[req.Mod.Path] = &requirement{mod: req.Mod, goMod: filepath.Join(dir, "go.mod")}
+			requireOrder = append(requireOrder, req.Mod.Path)
+		}
+	}
+	var useProjects []*Project
+	if workFile := goWorkFile(p.Path); workFile != "" {
+		if work := parseGoWork(workFile); work != nil {
+			workDir := filepath.Dir(workFile)
+			resolver.addReplaces(workDir, work.Replace)
+			for _, use := range work.Use {
+				dir := use.Path
+				if !filepath.IsAbs(dir) {
+					dir = filepath.Join(workDir, dir)
+				}
+				if filepath.Clean(dir) == filepath.Clean(p.Path) {
+					continue
+				}
+				useProject := &Project{
+					Path:   dir,
+					Simple: true,
+				}
+				if err := useProject.ParseModule(); err != nil {
+					Warnf(token.Position{Filename: workFile}, "parse module %s failed: %v", dir, err)
+					continue
+				}
+				modules[useProject.Module] = true
+				mp.Projects = append(mp.Projects, useProject)
+				useProjects = append(useProjects, useProject)
+			}
+		}
+	} else if useVendor(p.Path) {
+		resolver.vendor = filepath.Join(p.Path, "vendor")
+	}
+	resolver.addReplaces(p.Path, p.Replace)
+	addRequires(p.Path, p.Require)
+	for _, useProject := range useProjects {
+		resolver.addReplaces(useProject.Path, useProject.Replace)
+		addRequires(useProject.Path, useProject.Require)
+	}
+	for _, path := range requireOrder {
+		if modules[path] {
+			continue
+		}
+		modules[path] = true
+		req := requires[path]
+		dep := Project{
+			Path:   resolver.resolve(req.mod),
+			Simple: true,
+		}
+		if err := dep.ParseModule(); err != nil {
+			Warnf(token.Position{Filename: req.goMod}, "parse module %s@%s in %s failed: %v", req.mod.Path, req.mod.Version, dep.Path, err)
+		}
+		// replace为fork时，go.mod中的module可能与require的不同，代码中import的是require的路径
+		dep.Module = path
+		mp.Projects = append(mp.Projects, &dep)
+	}
+}
+
+func parseGoWork(workFile string) *modfile.WorkFile {
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		Errorf(token.Position{Filename: workFile}, "read go.work failed: %v", err)
+		return nil
+	}
+	work, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		Errorf(token.Position{Filename: workFile}, "parse go.work failed: %v", err)
+		return nil
+	}
+	return work
+}
+
 var GlobalProject *MainProject
 
 func CreateProject(path string, cfg *Config) *MainProject {
 	GlobalProject = &MainProject{
-		Cfg:      cfg,
-		Packages: make(map[string]*Package),
+		Cfg:       cfg,
+		Packages:  make(map[string]*Package),
+		loadLimit: make(chan struct{}, runtime.GOMAXPROCS(0)),
 		// initiatorMap: make(map[*Struct]*Initiators),
 		// servers:      make(map[string]*server),
 		// creators: make(map[*Struct]*Initiator),
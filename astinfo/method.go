@@ -1,7 +1,6 @@
 package astinfo
 
 import (
-	"fmt"
 	"go/ast"
 )
 
@@ -12,9 +11,12 @@ type Method struct {
 
 // new
 func NewMethod(funcDecl *ast.FuncDecl, goSource *Gosourse) *Method {
-	return &Method{
+	method := &Method{
 		Function: *NewFunction(funcDecl, goSource),
 	}
+	// Function是值拷贝，owner需要指向拷贝后的Function
+	method.Comment.owner = &method.Function
+	return method
 }
 
 // 解析method
@@ -29,6 +31,13 @@ func (m *Method) Parse() error {
 }
 
 func (m *Method) parseReceiver() error {
+	if info := m.GoSource.Pkg.typesInfo; info != nil {
+		if receiver := receiverOf(m.funcDecl, info); receiver != nil {
+			m.Receiver = receiver
+			receiver.MethodManager.AddCallable(m)
+			return nil
+		}
+	}
 	// 方法体为空
 	recvType := m.funcDecl.Recv.List[0].Type
 	var nameIndent *ast.Ident
@@ -47,8 +56,8 @@ out:
 			// func (a *Clas[K ClassA,V ClassB])
 			recvType = recvType1.X
 		default:
-			fmt.Printf("unexpected receiver type: %T in %s\n", recvType, m.GoSource.Path)
-			break out
+			Warnf(m.GoSource.Position(recvType.Pos()), "unexpected receiver type: %T", recvType)
+			return nil
 		}
 	}
 	// 由于代码的位置关系，这一步不一定会找到，所以自己创建了。
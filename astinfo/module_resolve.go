@@ -0,0 +1,171 @@
+package astinfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+var (
+	goEnvLock  sync.Mutex
+	goEnvCache = make(map[string]string)
+)
+
+// goEnv 读取go的环境变量，优先使用环境变量，其次使用go env的结果（包含go env -w设置的值）
+func goEnv(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	goEnvLock.Lock()
+	defer goEnvLock.Unlock()
+	if value, ok := goEnvCache[name]; ok {
+		return value
+	}
+	var value string
+	if output, err := exec.Command("go", "env", name).Output(); err == nil {
+		value = strings.TrimSpace(string(output))
+	}
+	goEnvCache[name] = value
+	return value
+}
+
+// goModCache 返回模块缓存目录，依次为GOMODCACHE，GOPATH中第一个目录下的pkg/mod，~/go/pkg/mod
+func goModCache() string {
+	if modCache := goEnv("GOMODCACHE"); modCache != "" {
+		return modCache
+	}
+	if goPath := filepath.SplitList(goEnv("GOPATH")); len(goPath) > 0 && goPath[0] != "" {
+		return filepath.Join(goPath[0], "pkg", "mod")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "go", "pkg", "mod")
+}
+
+// goRoot 返回go的安装目录
+func goRoot() string {
+	if root := goEnv("GOROOT"); root != "" {
+		return root
+	}
+	return runtime.GOROOT()
+}
+
+// sysPackagePath 返回标准库package的目录；标准库依赖的golang.org/x/...在GOROOT/src/vendor下；
+func sysPackagePath(pkgPath string) string {
+	src := filepath.Join(goRoot(), "src")
+	dir := filepath.Join(src, pkgPath)
+	if _, err := os.Stat(dir); err != nil {
+		if vendorDir := filepath.Join(src, "vendor", pkgPath); isDir(vendorDir) {
+			return vendorDir
+		}
+	}
+	return dir
+}
+
+// modCachePath 返回模块在模块缓存中的目录
+func modCachePath(modPath, version string) string {
+	escapedPath, err := module.EscapePath(modPath)
+	if err != nil {
+		escapedPath = modPath
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		escapedVersion = version
+	}
+	return filepath.Join(goModCache(), escapedPath+"@"+escapedVersion)
+}
+
+// goWorkFile 返回项目所在的go.work文件；GOWORK=off时不使用workspace，未设置时从项目目录向上查找；
+func goWorkFile(projectPath string) string {
+	switch work := os.Getenv("GOWORK"); work {
+	case "off":
+		return ""
+	case "":
+	default:
+		return work
+	}
+	for dir := projectPath; ; {
+		file := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// useVendor 与go命令一致：存在vendor/modules.txt且没有指定-mod=mod或-mod=readonly时，依赖从vendor目录读取
+func useVendor(projectPath string) bool {
+	if !isFile(filepath.Join(projectPath, "vendor", "modules.txt")) {
+		return false
+	}
+	for _, flag := range strings.Fields(goEnv("GOFLAGS")) {
+		if flag == "-mod=mod" || flag == "-mod=readonly" {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleResolver 根据go.work，replace，vendor和模块缓存计算依赖模块的目录
+type moduleResolver struct {
+	replaces []*modfile.Replace // 本地路径已转换为绝对路径
+	vendor   string             // vendor目录，为空时不使用vendor
+}
+
+// addReplaces 添加replace，本地路径相对于dir，即go.mod或go.work所在的目录；
+func (r *moduleResolver) addReplaces(dir string, replaces []*modfile.Replace) {
+	for _, replace := range replaces {
+		replace := *replace
+		if replace.New.Version == "" && !filepath.IsAbs(replace.New.Path) {
+			replace.New.Path = filepath.Join(dir, replace.New.Path)
+		}
+		r.replaces = append(r.replaces, &replace)
+	}
+}
+
+// resolve 返回依赖模块的目录；replace中指定了版本时仅替换该版本，未指定时替换所有版本；
+// 同一个模块有多个replace时，先添加的优先，即go.work中的replace优先于go.mod中的；
+func (r *moduleResolver) resolve(mod module.Version) string {
+	if r.vendor != "" {
+		return filepath.Join(r.vendor, mod.Path)
+	}
+	var matched *modfile.Replace
+	for _, replace := range r.replaces {
+		if replace.Old.Path != mod.Path {
+			continue
+		}
+		if replace.Old.Version == mod.Version {
+			matched = replace
+			break
+		}
+		if replace.Old.Version == "" && matched == nil {
+			matched = replace
+		}
+	}
+	if matched == nil {
+		return modCachePath(mod.Path, mod.Version)
+	}
+	if matched.New.Version != "" {
+		return modCachePath(matched.New.Path, matched.New.Version)
+	}
+	return matched.New.Path
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
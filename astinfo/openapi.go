@@ -0,0 +1,378 @@
+package astinfo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// OpenAPI 3.1 文档；go-openapi/spec仅支持2.0，所以此处定义3.1用到的结构；
+// 文档由Swagger生成的2.0文档转换而来，所以servlet和struct的解析结果只需要维护一份；
+// schema沿用spec.Schema，3.1的schema即json schema，spec.Schema可以直接表达；
+type OpenAPI struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       *spec.Info                  `json:"info"`
+	Servers    []OpenAPIServer             `json:"servers,omitempty"`
+	Tags       []spec.Tag                  `json:"tags,omitempty"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents           `json:"components"`
+	TagGroups  any                         `json:"x-tagGroups,omitempty"`
+}
+
+type OpenAPIServer struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]spec.Schema            `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type OpenAPIPathItem struct {
+	Get     *OpenAPIOperation `json:"get,omitempty"`
+	Put     *OpenAPIOperation `json:"put,omitempty"`
+	Post    *OpenAPIOperation `json:"post,omitempty"`
+	Delete  *OpenAPIOperation `json:"delete,omitempty"`
+	Options *OpenAPIOperation `json:"options,omitempty"`
+	Head    *OpenAPIOperation `json:"head,omitempty"`
+	Patch   *OpenAPIOperation `json:"patch,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	Tags        []string                   `json:"tags,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	OperationID string                     `json:"operationId,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	ErrorCodes  any                        `json:"x-error-codes,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string       `json:"name"`
+	In          string       `json:"in"`
+	Description string       `json:"description,omitempty"`
+	Required    bool         `json:"required,omitempty"`
+	Schema      *spec.Schema `json:"schema,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Description string                      `json:"description,omitempty"`
+	Required    bool                        `json:"required,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *spec.Schema `json:"schema,omitempty"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// 2.0不支持cookie参数，所有cookie参数合并为一个Cookie头，并用x-cookie记录cookie名的列表，转换为3.1时还原
+const cookieExtension = "x-cookie"
+
+// 2.0中指针字段用x-nullable标记，转换为3.1时变为 type:[xx,"null"] 或 oneOf:[$ref,{type:"null"}]
+const nullableExtension = "x-nullable"
+
+const (
+	definitionsPrefix = "#/definitions/"
+	componentsPrefix  = "#/components/schemas/"
+)
+
+// convertToOpenAPI31 将2.0文档转换为3.1文档；urlPrefix从path中去掉，放在servers中；
+func convertToOpenAPI31(swag *spec.Swagger, urlPrefix string) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.1.0",
+		Tags:    swag.Tags,
+		Paths:   make(map[string]*OpenAPIPathItem),
+		Components: OpenAPIComponents{
+			Schemas:         make(map[string]spec.Schema),
+			SecuritySchemes: make(map[string]*OpenAPISecurityScheme),
+		},
+	}
+	// 3.1要求info中有title和version；复制一份再修改，不影响同时输出的2.0文档
+	doc.Info = &spec.Info{}
+	if swag.Info != nil {
+		info := *swag.Info
+		doc.Info = &info
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = GlobalProject.currentProject.Module
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+	if tagGroups, ok := swag.Extensions[tagGroupsExtension]; ok {
+		doc.TagGroups = tagGroups
+	}
+	if urlPrefix != "" {
+		doc.Servers = append(doc.Servers, OpenAPIServer{Url: urlPrefix})
+	}
+	for name, schema := range swag.Definitions {
+		doc.Components.Schemas[name] = convertSchema(schema)
+	}
+	for name, scheme := range swag.SecurityDefinitions {
+		doc.Components.SecuritySchemes[name] = convertSecurityScheme(scheme)
+	}
+	// 只去掉完整的路径段，如前缀/api不匹配/apix；NewSwagger中已去掉结尾的/
+	if swag.Paths != nil {
+		for url, item := range swag.Paths.Paths {
+			if urlPrefix != "" && (url == urlPrefix || strings.HasPrefix(url, urlPrefix+"/")) {
+				url = "/" + strings.TrimLeft(url[len(urlPrefix):], "/")
+			}
+			doc.Paths[url] = convertPathItem(item, swag)
+		}
+	}
+	return doc
+}
+
+func convertPathItem(item spec.PathItem, swag *spec.Swagger) *OpenAPIPathItem {
+	return &OpenAPIPathItem{
+		Get:     convertOperation(item.Get, item.Parameters, swag),
+		Put:     convertOperation(item.Put, item.Parameters, swag),
+		Post:    convertOperation(item.Post, item.Parameters, swag),
+		Delete:  convertOperation(item.Delete, item.Parameters, swag),
+		Options: convertOperation(item.Options, item.Parameters, swag),
+		Head:    convertOperation(item.Head, item.Parameters, swag),
+		Patch:   convertOperation(item.Patch, item.Parameters, swag),
+	}
+}
+
+// 取operation的consumes/produces，没有则使用文档的，再没有则为application/json
+func mediaTypes(own, global []string) []string {
+	if len(own) != 0 {
+		return own
+	}
+	if len(global) != 0 {
+		return global
+	}
+	return []string{"application/json"}
+}
+
+func convertOperation(op *spec.Operation, common []spec.Parameter, swag *spec.Swagger) *OpenAPIOperation {
+	if op == nil {
+		return nil
+	}
+	result := &OpenAPIOperation{
+		Tags:        op.Tags,
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.ID,
+		Deprecated:  op.Deprecated,
+		Security:    op.Security,
+		Responses:   make(map[string]OpenAPIResponse),
+	}
+	if errorCodes, ok := op.Extensions[errorCodesExtension]; ok {
+		result.ErrorCodes = errorCodes
+	}
+	consumes := mediaTypes(op.Consumes, swag.Consumes)
+	var formSchema *spec.Schema
+	for _, param := range append(common, op.Parameters...) {
+		switch param.In {
+		case "body":
+			// body参数按照consumes转换为多种content的requestBody
+			schema := convertSchema(*param.Schema)
+			result.RequestBody = &OpenAPIRequestBody{
+				Description: param.Description,
+				Required:    param.Required,
+				Content:     make(map[string]OpenAPIMediaType),
+			}
+			for _, contentType := range consumes {
+				result.RequestBody.Content[contentType] = OpenAPIMediaType{Schema: &schema}
+			}
+		case "formData":
+			if formSchema == nil {
+				formSchema = &spec.Schema{}
+				formSchema.Typed("object", "")
+			}
+			formSchema.SetProperty(param.Name, simpleSchema(param))
+			if param.Required {
+				formSchema.AddRequired(param.Name)
+			}
+		default:
+			if names, ok := param.Extensions.GetStringSlice(cookieExtension); ok {
+				for _, name := range names {
+					result.Parameters = append(result.Parameters, OpenAPIParameter{
+						Name:   name,
+						In:     "cookie",
+						Schema: new(spec.Schema).Typed("string", ""),
+					})
+				}
+				continue
+			}
+			schema := simpleSchema(param)
+			result.Parameters = append(result.Parameters, OpenAPIParameter{
+				Name:        param.Name,
+				In:          param.In,
+				Description: param.Description,
+				Required:    param.Required || param.In == "path",
+				Schema:      &schema,
+			})
+		}
+	}
+	if formSchema != nil && result.RequestBody == nil {
+		result.RequestBody = &OpenAPIRequestBody{
+			Required: len(formSchema.Required) > 0,
+			Content: map[string]OpenAPIMediaType{
+				"application/x-www-form-urlencoded": {Schema: formSchema},
+				"multipart/form-data":               {Schema: formSchema},
+			},
+		}
+	}
+	if op.Responses != nil {
+		produces := mediaTypes(op.Produces, swag.Produces)
+		if op.Responses.Default != nil {
+			result.Responses["default"] = convertResponse(*op.Responses.Default, produces)
+		}
+		for code, response := range op.Responses.StatusCodeResponses {
+			result.Responses[strconv.Itoa(code)] = convertResponse(response, produces)
+		}
+	}
+	return result
+}
+
+func convertResponse(response spec.Response, produces []string) OpenAPIResponse {
+	result := OpenAPIResponse{
+		Description: response.Description,
+	}
+	// 3.1中description是必须的
+	if result.Description == "" {
+		result.Description = "success"
+	}
+	if response.Schema != nil {
+		schema := convertSchema(*response.Schema)
+		result.Content = make(map[string]OpenAPIMediaType)
+		for _, contentType := range produces {
+			result.Content[contentType] = OpenAPIMediaType{Schema: &schema}
+		}
+	}
+	return result
+}
+
+// 非body参数在2.0中直接定义type，3.1中需要放在schema中
+func simpleSchema(param spec.Parameter) spec.Schema {
+	schema := spec.Schema{}
+	schema.Type = spec.StringOrArray{param.Type}
+	schema.Format = param.Format
+	schema.Default = param.Default
+	schema.Enum = param.Enum
+	if param.Items != nil {
+		items := spec.Schema{}
+		items.Type = spec.StringOrArray{param.Items.Type}
+		items.Format = param.Items.Format
+		items.Enum = param.Items.Enum
+		schema.Items = &spec.SchemaOrArray{Schema: &items}
+	}
+	if param.Schema != nil {
+		schema = convertSchema(*param.Schema)
+	}
+	return schema
+}
+
+func convertSecurityScheme(scheme *spec.SecurityScheme) *OpenAPISecurityScheme {
+	result := &OpenAPISecurityScheme{
+		Type:        scheme.Type,
+		Description: scheme.Description,
+		Name:        scheme.Name,
+		In:          scheme.In,
+	}
+	if scheme.Type == "basic" {
+		result.Type = "http"
+		result.Scheme = "basic"
+	}
+	// 2.0没有bearer，用x-scheme扩展记录
+	if value, ok := scheme.Extensions.GetString("x-scheme"); ok {
+		result.Type = "http"
+		result.Scheme = value
+		result.Name = ""
+		result.In = ""
+	}
+	return result
+}
+
+// convertSchema 转换$ref的路径和nullable，并递归处理子schema
+func convertSchema(schema spec.Schema) spec.Schema {
+	nullable, _ := schema.Extensions.GetBool(nullableExtension)
+	if nullable {
+		extensions := spec.Extensions{}
+		for key, value := range schema.Extensions {
+			if key != nullableExtension {
+				extensions[key] = value
+			}
+		}
+		schema.Extensions = extensions
+	}
+	if ref := schema.Ref.String(); ref != "" {
+		schema.Ref = spec.MustCreateRef(componentsPrefix + strings.TrimPrefix(ref, definitionsPrefix))
+	}
+	if len(schema.Properties) != 0 {
+		properties := make(spec.SchemaProperties, len(schema.Properties))
+		for name, property := range schema.Properties {
+			properties[name] = convertSchema(property)
+		}
+		schema.Properties = properties
+	}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		items := convertSchema(*schema.Items.Schema)
+		schema.Items = &spec.SchemaOrArray{Schema: &items}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		additional := convertSchema(*schema.AdditionalProperties.Schema)
+		schema.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: &additional}
+	}
+	schema.AllOf = convertSchemas(schema.AllOf)
+	schema.OneOf = convertSchemas(schema.OneOf)
+	schema.AnyOf = convertSchemas(schema.AnyOf)
+	if nullable {
+		schema = nullableSchema(schema)
+	}
+	return schema
+}
+
+func convertSchemas(schemas []spec.Schema) []spec.Schema {
+	if len(schemas) == 0 {
+		return schemas
+	}
+	result := make([]spec.Schema, len(schemas))
+	for i, schema := range schemas {
+		result[i] = convertSchema(schema)
+	}
+	return result
+}
+
+// $ref不能和type同时使用，所以用oneOf表达nullable；其他情况在type中添加null
+func nullableSchema(schema spec.Schema) spec.Schema {
+	nullType := spec.Schema{}
+	nullType.Typed("null", "")
+	if schema.Ref.String() != "" {
+		ref := spec.Schema{}
+		ref.Ref = schema.Ref
+		schema.Ref = spec.Ref{}
+		schema.OneOf = []spec.Schema{ref, nullType}
+		return schema
+	}
+	if len(schema.Type) == 0 {
+		return schema
+	}
+	if !schema.Type.Contains("null") {
+		schema.Type = append(schema.Type, "null")
+	}
+	return schema
+}
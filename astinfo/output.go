@@ -0,0 +1,120 @@
+package astinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Output 生成的文件的写入目标；默认写入磁盘，gos check时写入内存，再与磁盘上的文件比较
+type Output interface {
+	// WriteFile 写入文件，name为绝对路径，目录不存在时自动创建
+	WriteFile(name string, content []byte) error
+	// Remove 删除旧版本生成的文件，文件不存在时不报错
+	Remove(name string) error
+}
+
+// GenOutput 所有生成的文件（gen目录，main.go，basic，swagger，错误码文档）都通过它写入
+var GenOutput Output = DiskOutput{}
+
+// DiskOutput 直接写入磁盘
+type DiskOutput struct{}
+
+// WriteFile 内容与磁盘上的文件相同时不写入，保持修改时间不变，gos watch不会把生成的文件当作修改
+func (DiskOutput) WriteFile(name string, content []byte) error {
+	if old, err := os.ReadFile(name); err == nil && bytes.Equal(old, content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(name, content, 0660)
+}
+
+func (DiskOutput) Remove(name string) error {
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// logSaved 文件写入磁盘后输出保存的位置，如swagger saved to xxx；写入内存时文件没有保存，不输出
+func logSaved(kind, name string) {
+	if _, ok := GenOutput.(DiskOutput); ok {
+		Logf("%s saved to %s\n", kind, name)
+	}
+}
+
+// MemoryOutput 将生成的文件保存在内存中，不修改磁盘
+type MemoryOutput struct {
+	lock    sync.Mutex
+	files   map[string][]byte
+	removed map[string]bool
+}
+
+func NewMemoryOutput() *MemoryOutput {
+	return &MemoryOutput{
+		files:   make(map[string][]byte),
+		removed: make(map[string]bool),
+	}
+}
+
+func (m *MemoryOutput) WriteFile(name string, content []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.files[name] = append([]byte(nil), content...)
+	delete(m.removed, name)
+	return nil
+}
+
+func (m *MemoryOutput) Remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.files, name)
+	m.removed[name] = true
+	return nil
+}
+
+// Files 返回写入的所有文件，key为文件名
+func (m *MemoryOutput) Files() map[string][]byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	files := make(map[string][]byte, len(m.files))
+	for name, content := range m.files {
+		files[name] = content
+	}
+	return files
+}
+
+// FileChange 生成的结果与磁盘上的文件不一致的文件；Old为nil表示磁盘上没有该文件，New为nil表示该文件需要删除
+type FileChange struct {
+	Name string
+	Old  []byte
+	New  []byte
+}
+
+// Changes 与磁盘上的文件比较，返回不一致的文件，按照文件名排序
+func (m *MemoryOutput) Changes() []FileChange {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var changes []FileChange
+	for _, name := range sortedKeys(m.files) {
+		content := m.files[name]
+		old, err := os.ReadFile(name)
+		if err == nil && string(old) == string(content) {
+			continue
+		}
+		changes = append(changes, FileChange{Name: name, Old: old, New: content})
+	}
+	for name := range m.removed {
+		if old, err := os.ReadFile(name); err == nil {
+			changes = append(changes, FileChange{Name: name, Old: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
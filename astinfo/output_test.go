@@ -0,0 +1,33 @@
+package astinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDiskOutputKeepsUnchangedFile 内容相同时不写入，gos watch依赖修改时间判断文件是否被修改
+func TestDiskOutputKeepsUnchangedFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "gen", "a.go")
+	output := DiskOutput{}
+	if err := output.WriteFile(name, []byte("package gen\n")); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := output.WriteFile(name, []byte("package gen\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(name); err != nil || !info.ModTime().Equal(old) {
+		t.Fatalf("unchanged file is rewritten: %v %v", info.ModTime(), err)
+	}
+	if err := output.WriteFile(name, []byte("package gen\n\nvar a int\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(name); err != nil || info.ModTime().Equal(old) {
+		t.Fatalf("changed file is not written: %v", err)
+	}
+}
@@ -1,13 +1,16 @@
 package astinfo
 
 import (
-	"fmt"
+	"errors"
 	"go/ast"
-	"go/parser"
+	"go/constant"
+	"go/scanner"
 	"go/token"
-	"path"
+	"go/types"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // Package 表示一个Go包的基本信息
@@ -20,14 +23,23 @@ type Package struct {
 	Structs map[string]*Struct // 包内结构体集合（key为结构体名称）
 	// Interfaces map[string]*Interface // key是Interface 的Name
 	// 由于采用了两层扫描，所以不再需要Types map了。直接调用get方法获取；
-	parsers   []Parser             // 先扫描文件，生成parsers,然后依次进行parser解析；
-	Types     map[string]Typer     // key是Type 的Name
-	fset      *token.FileSet       // 记录fset，到时可以找到文件
-	Files     map[string]*ast.File // Go source files by filename
-	GlobalVar map[string]*VarField
-	WaitTyper map[string][]*Typer // 有些类型先被使用，再定义，此时在此处将内容缓存下载，最后统一解析；
+	parsers    []Parser             // 先扫描文件，生成parsers,然后依次进行parser解析；
+	Types      map[string]Typer     // key是Type 的Name
+	fset       *token.FileSet       // 记录fset，到时可以找到文件
+	Files      map[string]*ast.File // Go source files by filename
+	GlobalVar  map[string]*VarField
+	Enums      map[string][]*EnumValue   // key是类型名，value是该类型的常量，按定义顺序排列
+	Consts     map[string]constant.Value // 包中可以计算出值的常量
+	constSpecs []*constSpec              // 包中所有的常量定义，所有文件解析完成后统一计算
+	constTypes map[string]bool           // 包中定义的类型名，计算常量时用于识别类型转换
+	ErrorCodes map[string]*ErrorCode     // 通过basic.New定义的错误变量，key为变量名
+	WaitTyper  map[string][]*Typer       // 有些类型先被使用，再定义，此时在此处将内容缓存下载，最后统一解析；
+	typesInfo  *types.Info               // 使用packages方式加载时，go/types的类型信息
 	FunctionManager
 	finshedParse bool
+	loadOnce     sync.Once   // 文件只加载一次，可以在多个goroutine中同时加载
+	goSources    []*Gosourse // 解析的源文件，保存缓存时记录每个文件的import
+	cacheKey     *packageKey // 解析缓存中的key，不使用缓存时为nil
 }
 
 //  Package中不包含goSource，因为
@@ -42,7 +54,7 @@ func (pkg *Package) GetName() string {
 	// 本模块需要解析的包都是有name的。但是第三方的包可能就没有，需要从Module Name中解析
 	// 等后续有需求了再做；
 	if name == "" {
-		fmt.Printf("failed to get name of pkg %s, use base name in path which is error\n", pkg.Module)
+		Infof(token.Position{Filename: pkg.Path}, "failed to get name of pkg %s, use base name in path", pkg.Module)
 		name = filepath.Base(pkg.Module)
 	}
 	return name
@@ -53,63 +65,113 @@ func (pkg *Package) AddParser(parser Parser) {
 	pkg.parsers = append(pkg.parsers, parser)
 }
 
-// 采用遇到遇到不认识的import就先深度parse的方法；
-func (pkg *Package) SimpleParse() error {
-
-	path := pkg.Path
-	defer func() {
-		for name, alias := range pkg.WaitTyper {
-			typer := pkg.GetTyper(name)
-			if typer != nil {
-				for _, typer1 := range alias {
-					*typer1 = typer
-				}
-			} else {
-				fmt.Printf("Error: failed to get %s.%s when parse finish\n", pkg.Path, name)
-			}
+// load 加载package的文件，只执行一次；其他goroutine同时加载该package时等待加载完成；
+// go/packages加载的package已经有文件，不再加载
+func (pkg *Package) load() {
+	pkg.loadOnce.Do(func() {
+		if pkg.fset == nil {
+			pkg.SimpleParse()
 		}
-		// fmt.Printf("finished Parsing package: %s\n", path)
-	}()
+	})
+}
+
+// SimpleParse 读取并解析package的文件，仅得到语法树，不解析类型；
+// 只访问package自己的字段，可以与其他package的SimpleParse并发执行；
+// 解析缓存有效时只读取包名，模型在Parse时从缓存中读取；
+func (pkg *Package) SimpleParse() error {
 	pkg.fset = token.NewFileSet()
+	if cache := GlobalProject.parseCache; cache != nil {
+		pkg.cacheKey = cache.lookup(pkg.Module)
+		if header := pkg.cacheKey.header; header != nil {
+			pkg.Name = header.Name
+			return nil
+		}
+	}
+	pkg.parseSources()
+	return nil
+}
+
+// parseSources 读取并解析满足build约束的文件；失败时报告错误并返回
+func (pkg *Package) parseSources() error {
 	// 这里取绝对路径，方便打印出来的语法树可以转跳到编辑器
 	// fmt.Printf("Parsing package: %s\n", path)
-	packageMap, err := parser.ParseDir(pkg.fset, path, nil, parser.AllErrors|parser.ParseComments)
+	// 仅解析满足build约束的文件，与go build编译的文件一致
+	sources, err := readSourceFiles(pkg.Path, GlobalProject.buildContext())
 	if err != nil {
-		fmt.Printf("parse package %s failed %s\n", pkg.Module, err.Error())
-		return nil
+		pkg.reportParseError(err)
+		return err
 	}
-	// 一个目录下可能有多
-	for packName, pack := range packageMap {
-		_ = packName
-		// 先跳过test,后续再说；
-		if strings.HasSuffix(packName, "_test") {
-			continue
-		}
-		for _, f := range pack.Files {
-			if isIgnoreFile(f) {
-				continue
-			}
-			pkg.Name = pack.Name
-			pkg.Files = pack.Files
-			break
-		}
+	if cache := GlobalProject.parseCache; cache != nil {
+		cache.record(pkg.Module)
+	}
+	name, files, err := parseSources(pkg.fset, sources)
+	if err != nil {
+		pkg.reportParseError(err)
+		return err
+	}
+	if len(files) > 0 {
+		pkg.Name = name
+		pkg.Files = files
 	}
 	return nil
 }
+
+// reportParseError 语法错误按照位置逐个报告；依赖包中的错误不影响生成，作为warning
+func (pkg *Package) reportParseError(err error) {
+	severity := SeverityError
+	if pkg.Simple {
+		severity = SeverityWarning
+	}
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		Diagnostics.Report(severity, token.Position{Filename: pkg.Path}, "parse package %s failed: %s", pkg.Module, err.Error())
+		return
+	}
+	for _, e := range list {
+		Diagnostics.Report(severity, e.Pos, "%s", e.Msg)
+	}
+}
+
 func (pkg *Package) Parse() error {
 	if pkg.finshedParse {
 		return nil
 	}
 	pkg.finshedParse = true
+	// 缓存中有模型时不再解析源码，否则解析源码，完成后保存模型
+	key := pkg.cacheKey
+	if key != nil && key.header != nil {
+		if model := GlobalProject.parseCache.readModel(pkg.Path, key.key); model != nil {
+			pkg.parseModel(key.header.Imports, model)
+			return nil
+		}
+		if pkg.parseSources() != nil {
+			key = nil
+		}
+	}
+	// 解析import时需要其中所有package的文件，先并发加载
+	var imports []string
 	for filename, f := range pkg.Files {
 		if strings.HasSuffix(filename, "_test.go") {
 			continue
 		}
-		gofile := NewGosourse(f, pkg, filename)
+		for _, importSpec := range f.Imports {
+			if importPath, err := strconv.Unquote(importSpec.Path.Value); err == nil {
+				imports = append(imports, importPath)
+			}
+		}
+	}
+	GlobalProject.prefetch(imports)
+	for _, filename := range sortedKeys(pkg.Files) {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		gofile := NewGosourse(pkg.Files[filename], pkg, filename)
 		gofile.Parse()
+		pkg.goSources = append(pkg.goSources, gofile)
 	}
+	pkg.evalConsts()
 	if pkg.Simple {
-		for _, parser := range pkg.Types {
+		for _, parser := range sortedValues(pkg.Types) {
 			parser.Parse()
 		}
 	} else {
@@ -117,7 +179,20 @@ func (pkg *Package) Parse() error {
 			parser.Parse()
 		}
 	}
-
+	// 先被使用，后定义的类型，在package解析完成后统一赋值
+	for name, alias := range pkg.WaitTyper {
+		typer := pkg.Types[name]
+		if typer != nil {
+			for _, typer1 := range alias {
+				*typer1 = typer
+			}
+		} else {
+			Warnf(token.Position{Filename: pkg.Path}, "failed to get %s.%s when parse finish", pkg.Module, name)
+		}
+	}
+	if key != nil && key.header != nil {
+		GlobalProject.parseCache.storeModel(pkg, key)
+	}
 	return nil
 }
 
@@ -130,15 +205,19 @@ func NewPackage(module string, simple bool, absPath string) *Package {
 		Path:    absPath,
 		Structs: make(map[string]*Struct),
 		// Interfaces: make(map[string]*Interface),
-		GlobalVar: make(map[string]*VarField),
-		Types:     make(map[string]Typer),
-		WaitTyper: make(map[string][]*Typer),
+		GlobalVar:  make(map[string]*VarField),
+		Enums:      make(map[string][]*EnumValue),
+		Consts:     make(map[string]constant.Value),
+		constTypes: make(map[string]bool),
+		ErrorCodes: make(map[string]*ErrorCode),
+		Types:      make(map[string]Typer),
+		WaitTyper:  make(map[string][]*Typer),
 		// finshedParse: simple,
 	}
 }
 
 func NewSysPackage(module string) *Package {
-	return NewPackage(module, true, path.Join("/opt/google/go/src", module))
+	return NewPackage(module, true, sysPackagePath(module))
 }
 
 func (pkg *Package) GetTyper(name string) Typer {
@@ -0,0 +1,407 @@
+package astinfo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// parseCacheVersion 缓存的格式或内容修改时增加，旧的缓存自动失效
+const parseCacheVersion = "2"
+
+// DefaultCacheDir 默认的缓存目录，相对工程根目录
+const DefaultCacheDir = ".gos/cache"
+
+// parseCache 以package为单位缓存解析得到的模型（类型，字段，注释，import，函数签名，错误码等）；
+// 每个package的缓存文件先保存头部(cachedPackage)，再保存模型(packageModel)；
+// package的key由自己的文件和所有import的package的key计算得到，package自己或者直接，间接依赖的package修改时key都会改变，
+// 此时重新解析源码，其他package直接从缓存中读取模型，不再读取语法树；
+// 标准库和模块缓存中的文件不会被修改，只使用目录计算key，不读取文件内容；
+type parseCache struct {
+	dir      string // 缓存目录
+	salt     string // 缓存版本，go.mod，go.sum和build约束的hash，修改时所有缓存失效
+	readOnly bool   // 只读取缓存，不写入
+	goRoot   string // 标准库的源码目录；GOROOT中没有VERSION文件（如源码编译的开发版本）时为空，按照文件内容计算key
+	modCache string // 模块缓存目录
+
+	lock   sync.Mutex
+	keys   map[string]*packageKey // key为package的全路径
+	parsed []string               // 从源码解析的package，按照解析的顺序排列
+}
+
+// cachedPackage 缓存文件的头部
+type cachedPackage struct {
+	Key      string
+	Self     string   // package自己的文件的hash，没有变化时直接使用Imports，不需要重新解析import
+	Name     string   // 包名
+	Imports  []string // 参与编译的文件import的package，已排序
+	HasModel bool     // 头部之后是否有模型；只计算了key，还没有被解析过的package只有头部
+}
+
+// packageKey package在本次运行中的key
+type packageKey struct {
+	key    string
+	header *cachedPackage // key一致的缓存头部；package的文件无法读取时为nil，需要解析源码并报告错误
+}
+
+// sourceFile 参与编译的源文件
+type sourceFile struct {
+	name    string
+	content []byte
+}
+
+// newParseCache 创建工程的缓存；cacheDir为"-"时不使用缓存；force时清除已有的缓存，重新解析所有package；
+// readOnly时只读取已有的缓存，失效的package解析原始文件，但不更新缓存
+func newParseCache(projectPath, cacheDir string, force, readOnly bool, ctxt *build.Context) *parseCache {
+	switch cacheDir {
+	case "-":
+		return nil
+	case "":
+		cacheDir = DefaultCacheDir
+	}
+	if !filepath.IsAbs(cacheDir) {
+		cacheDir = filepath.Join(projectPath, cacheDir)
+	}
+	if force && !readOnly {
+		os.RemoveAll(cacheDir)
+	}
+	cache := &parseCache{
+		dir:      cacheDir,
+		readOnly: readOnly,
+		modCache: goModCache(),
+		keys:     make(map[string]*packageKey),
+	}
+	hash := sha256.New()
+	hash.Write([]byte(parseCacheVersion + "\x00"))
+	for _, name := range []string{"go.mod", "go.sum"} {
+		content, _ := os.ReadFile(filepath.Join(projectPath, name))
+		hash.Write(content)
+		hash.Write([]byte{0})
+	}
+	fmt.Fprintln(hash, ctxt.GOOS, ctxt.GOARCH, ctxt.CgoEnabled, ctxt.BuildTags, ctxt.ReleaseTags)
+	if version, err := os.ReadFile(filepath.Join(goRoot(), "VERSION")); err == nil {
+		cache.goRoot = filepath.Join(goRoot(), "src")
+		hash.Write(version)
+	}
+	cache.salt = hex.EncodeToString(hash.Sum(nil))
+	return cache
+}
+
+// immutable 标准库和模块缓存中的package
+func (c *parseCache) immutable(dir string) bool {
+	inDir := func(root string) bool {
+		return root != "" && strings.HasPrefix(dir, root+string(filepath.Separator))
+	}
+	return inDir(c.goRoot) || inDir(c.modCache)
+}
+
+// lookup 计算package的key，返回key和key一致的缓存头部；可以在多个goroutine中同时调用
+func (c *parseCache) lookup(module string) *packageKey {
+	return c.keyOf(module, nil)
+}
+
+// keyOf chain为正在计算key的package，go不允许循环import，这里只保证不会无限递归
+func (c *parseCache) keyOf(module string, chain []string) *packageKey {
+	c.lock.Lock()
+	key := c.keys[module]
+	c.lock.Unlock()
+	if key != nil {
+		return key
+	}
+	if slices.Contains(chain, module) {
+		return &packageKey{key: "cycle"}
+	}
+	pkg := GlobalProject.newPackage(module)
+	header := c.readHeader(pkg.Path)
+	self, name, imports, ok := c.selfKey(pkg, header)
+	hash := sha256.New()
+	hash.Write([]byte(self + "\x00"))
+	chain = append(chain, module)
+	for _, imp := range imports {
+		hash.Write([]byte(imp + "\x00" + c.keyOf(imp, chain).key + "\x00"))
+	}
+	key = &packageKey{key: hex.EncodeToString(hash.Sum(nil))}
+	if ok {
+		if header == nil || header.Key != key.key {
+			// 先保存头部，之后的运行不需要再解析import；模型在package解析完成后保存
+			header = &cachedPackage{Key: key.key, Self: self, Name: name, Imports: imports}
+			c.store(pkg.Path, header, nil)
+		}
+		key.header = header
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if existing := c.keys[module]; existing != nil {
+		return existing
+	}
+	c.keys[module] = key
+	return key
+}
+
+// selfKey 计算package自己的文件的hash，返回包名和import的package；文件无法读取时ok为false
+func (c *parseCache) selfKey(pkg *Package, header *cachedPackage) (self, name string, imports []string, ok bool) {
+	hash := sha256.New()
+	hash.Write([]byte(c.salt + "\x00" + pkg.Module + "\x00" + pkg.Path + "\x00" + strconv.FormatBool(pkg.Simple) + "\x00"))
+	var sources []sourceFile
+	immutable := c.immutable(pkg.Path)
+	if !immutable {
+		var err error
+		if sources, err = readSourceFiles(pkg.Path, GlobalProject.buildContext()); err != nil {
+			return "", "", nil, false
+		}
+		for _, source := range sources {
+			hash.Write([]byte(source.name + "\x00"))
+			hash.Write(source.content)
+			hash.Write([]byte{0})
+		}
+	}
+	self = hex.EncodeToString(hash.Sum(nil))
+	if header != nil && header.Self == self {
+		return self, header.Name, header.Imports, true
+	}
+	if immutable {
+		var err error
+		if sources, err = readSourceFiles(pkg.Path, GlobalProject.buildContext()); err != nil {
+			return "", "", nil, false
+		}
+	}
+	name, imports = importsOf(sources)
+	return self, name, imports, true
+}
+
+// importsOf 只解析文件的import，返回包名和import的package；与解析源码时一致，只使用与第一个文件包名相同的文件
+func importsOf(sources []sourceFile) (string, []string) {
+	var name string
+	fset := token.NewFileSet()
+	imports := make(map[string]bool)
+	for _, source := range sources {
+		f, _ := parser.ParseFile(fset, source.name, source.content, parser.ImportsOnly)
+		if f == nil || f.Name == nil {
+			continue
+		}
+		if name == "" {
+			name = f.Name.Name
+		}
+		if f.Name.Name != name {
+			continue
+		}
+		for _, spec := range f.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			// gen目录中的代码由gos生成，不参与解析
+			if err != nil || importPath == "C" || importPath == GlobalProject.currentProject.Module+"/gen" {
+				continue
+			}
+			imports[importPath] = true
+		}
+	}
+	return name, sortedKeys(imports)
+}
+
+func (c *parseCache) path(pkgDir string) string {
+	sum := sha256.Sum256([]byte(pkgDir))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:12])+".gob")
+}
+
+// readHeader 读取package缓存的头部，不存在或格式错误时返回nil
+func (c *parseCache) readHeader(pkgDir string) *cachedPackage {
+	file, err := os.Open(c.path(pkgDir))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var header cachedPackage
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&header); err != nil {
+		return nil
+	}
+	return &header
+}
+
+// readModel 读取package的模型，key不一致或者没有模型时返回nil
+func (c *parseCache) readModel(pkgDir, key string) *packageModel {
+	file, err := os.Open(c.path(pkgDir))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	decoder := gob.NewDecoder(bufio.NewReader(file))
+	var header cachedPackage
+	if err := decoder.Decode(&header); err != nil || header.Key != key || !header.HasModel {
+		return nil
+	}
+	var model packageModel
+	if err := decoder.Decode(&model); err != nil {
+		return nil
+	}
+	return &model
+}
+
+// store 保存package的缓存，model为nil时只保存头部；先写入临时文件再重命名，避免读到写了一半的缓存
+func (c *parseCache) store(pkgDir string, header *cachedPackage, model *packageModel) {
+	if c.readOnly {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0750); err != nil {
+		return
+	}
+	// 缓存不需要提交到git
+	if ignore := filepath.Join(c.dir, ".gitignore"); !isFile(ignore) {
+		os.WriteFile(ignore, []byte("*\n"), 0640)
+	}
+	var content bytes.Buffer
+	encoder := gob.NewEncoder(&content)
+	if err := encoder.Encode(header); err != nil {
+		return
+	}
+	if model != nil {
+		if err := encoder.Encode(model); err != nil {
+			return
+		}
+	}
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(content.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(pkgDir))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// storeModel package从源码解析完成后保存模型
+func (c *parseCache) storeModel(pkg *Package, key *packageKey) {
+	if c.readOnly {
+		return
+	}
+	header := *key.header
+	header.HasModel = true
+	c.store(pkg.Path, &header, pkg.model())
+}
+
+// record 记录从源码解析的package
+func (c *parseCache) record(module string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.parsed = append(c.parsed, module)
+}
+
+// readSourceFiles 读取目录下满足build约束的源文件，按照文件名排序；测试文件不参与解析
+func readSourceFiles(dir string, ctxt *build.Context) ([]sourceFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var sources []sourceFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		match, err := ctxt.MatchFile(dir, name)
+		if err != nil {
+			Warnf(token.Position{Filename: filename}, "check build constraints failed: %s", err.Error())
+		}
+		if !match {
+			continue
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, sourceFile{name: filename, content: content})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].name < sources[j].name
+	})
+	return sources, nil
+}
+
+// parseSources 解析package的源文件，返回包名和文件的语法树
+func parseSources(fset *token.FileSet, sources []sourceFile) (string, map[string]*ast.File, error) {
+	var name string
+	files := make(map[string]*ast.File)
+	for _, source := range sources {
+		f, err := parser.ParseFile(fset, source.name, source.content, parser.AllErrors|parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+		// 同一个目录下可能有多个package，如package main的工具脚本，使用第一个文件的package
+		if name == "" {
+			name = f.Name.Name
+		}
+		if f.Name.Name != name {
+			continue
+		}
+		files[source.name] = f
+	}
+	return name, files, nil
+}
+
+// DeclarationHash 返回文件中声明部分（类型，变量，常量，函数签名和注释）的hash，函数体不参与计算；
+// gos watch用来判断修改是否需要重新生成代码；文件有语法错误时使用整个文件的hash
+func DeclarationHash(filename string, content []byte) string {
+	hash := sha256.New()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, content, parser.SkipObjectResolution)
+	if err != nil {
+		hash.Write(content)
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+	file := fset.File(f.Pos())
+	last := 0
+	for _, body := range stripFuncBody(f) {
+		lbrace, rbrace := file.Offset(body.Lbrace), file.Offset(body.Rbrace)
+		hash.Write(content[last : lbrace+1])
+		last = rbrace
+	}
+	hash.Write(content[last:])
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// stripFuncBody 去掉函数体和函数体中的注释，gos只使用函数的签名和文档注释；返回去掉的函数体
+func stripFuncBody(file *ast.File) []*ast.BlockStmt {
+	var bodies []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Body != nil {
+			bodies = append(bodies, funcDecl.Body)
+			funcDecl.Body = nil
+		}
+	}
+	if len(bodies) == 0 {
+		return nil
+	}
+	// 函数和注释都按照位置排序
+	comments := file.Comments[:0]
+	i := 0
+	for _, comment := range file.Comments {
+		for i < len(bodies) && bodies[i].Rbrace < comment.Pos() {
+			i++
+		}
+		if i < len(bodies) && comment.Pos() > bodies[i].Lbrace {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	file.Comments = comments
+	return bodies
+}
@@ -0,0 +1,605 @@
+package astinfo
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// packageModel 解析缓存中保存的package模型，从缓存中读取的结果与解析源码一致；
+// 类型之间的引用保存为package路径和类型名，读取时通过FindPackage找到对应的类型，与解析源码时一样按需解析其他package；
+// 位置保存为token.Pos，读取时按照原始文件的大小和行信息重建FileSet，得到的位置与解析源码时一致（不支持//line）；
+// 注释保存原始文本，读取时重新解析；
+type packageModel struct {
+	Files       []fileModel      // FileSet中的文件，按照添加的顺序排列
+	Sources     []sourceModel    // 参与解析的文件
+	Types       []*typeDeclModel // 按照解析源码时类型的解析顺序排列
+	Vars        []*varModel
+	Funcs       []*funcModel // 有gos注释的函数和方法，按照定义的顺序排列
+	Enums       []enumModel
+	ErrorCodes  []errorCodeModel
+	Diagnostics []diagnosticModel // 解析package时报告的，位于package中的诊断信息
+}
+
+type fileModel struct {
+	Name  string
+	Base  int
+	Size  int
+	Lines []int
+}
+
+type sourceModel struct {
+	Path    string
+	Imports map[string]string
+}
+
+type commentModel struct {
+	Slash token.Pos
+	Text  string
+}
+
+type identModel struct {
+	Name string
+	Pos  token.Pos
+}
+
+type declKind uint8
+
+const (
+	declStruct declKind = iota
+	declInterface
+	declAlias
+)
+
+// typeDeclModel 结构体，interface和类型定义
+type typeDeclModel struct {
+	Kind       declKind
+	Name       string
+	File       string
+	Pos        token.Pos
+	Doc        []commentModel
+	Equal      bool
+	TypeParams []*fieldModel // 范型参数，Type为约束
+	Fields     []*fieldModel // 结构体的字段
+	Methods    []*funcModel  // client interface的方法
+	Type       *typeModel    // 类型定义的原始类型
+}
+
+type fieldModel struct {
+	Name    string
+	Pos     token.Pos // 字段名的位置，匿名字段为类型的位置
+	Type    *typeModel
+	Tag     string // 包括引号的原始tag
+	TagPos  token.Pos
+	Comment []commentModel // 行尾注释
+}
+
+type varModel struct {
+	File    string
+	Names   []identModel
+	Type    *typeModel
+	Doc     []commentModel
+	Comment []commentModel
+}
+
+// funcModel 函数，方法和interface的方法
+type funcModel struct {
+	Name    string
+	File    string
+	Pos     token.Pos // 函数为func关键字的位置，interface的方法为方法名的位置
+	NamePos token.Pos
+	Method  bool
+	Recv    string // 方法所属的结构体
+	Doc     []commentModel
+	Params  []*fieldModel
+	Results []*fieldModel
+}
+
+type enumModel struct {
+	Type   string
+	Values []EnumValue
+}
+
+type errorCodeModel struct {
+	Name    string
+	Comment string
+	Code    any
+	Message string
+}
+
+type diagnosticModel struct {
+	Severity int
+	Position token.Position
+	Message  string
+}
+
+type typeKind uint8
+
+const (
+	kindNil       typeKind = iota // 无法解析的类型
+	kindRaw                       // 原始类型
+	kindNamed                     // package中定义的结构体，interface和类型
+	kindPointer                   // 指针
+	kindArray                     // 数组和切片
+	kindMap                       // map
+	kindInstance                  // 范型的实例化
+	kindParam                     // 范型参数
+	kindStruct                    // 匿名结构体
+	kindInterface                 // 匿名interface
+	kindFunc                      // 函数
+	kindChan                      // chan
+)
+
+// typeModel Typer在缓存中的格式；gob不支持数组中的nil，无法解析的类型保存为kindNil
+type typeModel struct {
+	Kind     typeKind
+	Name     string // 原始类型名，类型名，范型参数名
+	Pkg      string // 定义类型的package路径
+	Index    int    // 范型参数的位置
+	Dir      ast.ChanDir
+	Variadic bool
+	Elem     *typeModel    // 指针，数组和chan的元素，map的value，范型实例化的原始类型
+	Key      *typeModel    // map的key
+	Args     []*typeModel  // 范型实例化的类型参数，函数的参数，匿名interface嵌入的interface
+	Results  []*typeModel  // 函数的返回值
+	Fields   []*fieldModel // 匿名结构体的字段
+	Methods  []*funcModel  // 匿名interface的方法
+}
+
+// model 返回package解析得到的模型，保存在解析缓存中
+func (pkg *Package) model() *packageModel {
+	model := &packageModel{}
+	files := map[string]bool{pkg.Path: true}
+	pkg.fset.Iterate(func(file *token.File) bool {
+		files[file.Name()] = true
+		model.Files = append(model.Files, fileModel{Name: file.Name(), Base: file.Base(), Size: file.Size(), Lines: file.Lines()})
+		return true
+	})
+	for _, goSource := range pkg.goSources {
+		model.Sources = append(model.Sources, sourceModel{Path: goSource.Path, Imports: goSource.Imports})
+	}
+	// 与Parse中的解析顺序一致，简单解析的package只解析类型
+	parsers := pkg.parsers
+	if pkg.Simple {
+		parsers = nil
+		for _, typer := range sortedValues(pkg.Types) {
+			parsers = append(parsers, typer)
+		}
+	}
+	for _, parser := range parsers {
+		switch parser := parser.(type) {
+		case *Struct:
+			model.Types = append(model.Types, &typeDeclModel{
+				Kind:       declStruct,
+				Name:       parser.StructName,
+				File:       parser.goSource.Path,
+				Pos:        parser.astRoot.Pos(),
+				Doc:        commentsModel(parser.astRoot.Doc),
+				TypeParams: typeParamsModel(parser.TypeParameter),
+				Fields:     fieldsModel(parser.Fields),
+			})
+		case *Interface:
+			decl := &typeDeclModel{
+				Kind: declInterface,
+				Name: parser.InterfaceName,
+				File: parser.GoSource.Path,
+				Pos:  parser.astRoot.Pos(),
+				Doc:  commentsModel(parser.astRoot.Doc),
+			}
+			for _, method := range parser.Methods {
+				decl.Methods = append(decl.Methods, interfaceFieldModel(method))
+			}
+			model.Types = append(model.Types, decl)
+		case *Alias:
+			model.Types = append(model.Types, &typeDeclModel{
+				Kind:       declAlias,
+				Name:       parser.Name,
+				File:       parser.Gosourse.Path,
+				Pos:        parser.astRoot.Pos(),
+				Equal:      parser.Equal,
+				TypeParams: typeParamsModel(parser.TypeParameter),
+				Type:       typeModelOf(parser.Typer),
+			})
+		case *VarFieldHelper:
+			root := parser.astRoot
+			if root.Type == nil || len(root.Names) == 0 {
+				continue
+			}
+			v := &varModel{
+				File:    parser.goSource.Path,
+				Type:    typeModelOf(pkg.GlobalVar[root.Names[0].Name].Type),
+				Doc:     commentsModel(root.Doc),
+				Comment: commentsModel(root.Comment),
+			}
+			for _, name := range root.Names {
+				v.Names = append(v.Names, identModel{Name: name.Name, Pos: name.Pos()})
+			}
+			model.Vars = append(model.Vars, v)
+		case *FunctionParserHelper:
+			if parser.Comment.funcType != "" {
+				model.Funcs = append(model.Funcs, functionModel(parser.Function))
+			}
+		case *Method:
+			if parser.Comment.funcType != "" {
+				fun := functionModel(&parser.Function)
+				fun.Method = true
+				if parser.Receiver != nil {
+					fun.Recv = parser.Receiver.StructName
+				}
+				model.Funcs = append(model.Funcs, fun)
+			}
+		}
+	}
+	for _, name := range sortedKeys(pkg.Enums) {
+		enum := enumModel{Type: name}
+		for _, value := range pkg.Enums[name] {
+			enum.Values = append(enum.Values, *value)
+		}
+		model.Enums = append(model.Enums, enum)
+	}
+	for _, errorCode := range sortedValues(pkg.ErrorCodes) {
+		code, message := errorCode.value()
+		model.ErrorCodes = append(model.ErrorCodes, errorCodeModel{
+			Name:    errorCode.Name,
+			Comment: errorCode.Comment,
+			Code:    code,
+			Message: message,
+		})
+	}
+	for _, diagnostic := range Diagnostics.filter(func(d *Diagnostic) bool { return files[d.Position.Filename] }) {
+		model.Diagnostics = append(model.Diagnostics, diagnosticModel{
+			Severity: int(diagnostic.Severity),
+			Position: diagnostic.Position,
+			Message:  diagnostic.Message,
+		})
+	}
+	return model
+}
+
+func commentsModel(group *ast.CommentGroup) []commentModel {
+	if group == nil {
+		return nil
+	}
+	var comments []commentModel
+	for _, comment := range group.List {
+		comments = append(comments, commentModel{Slash: comment.Slash, Text: comment.Text})
+	}
+	return comments
+}
+
+func typeParamsModel(params []*Field) []*fieldModel {
+	var result []*fieldModel
+	for _, param := range params {
+		var constraint Typer
+		if typeParam, ok := param.Type.(*TypeParam); ok {
+			constraint = typeParam.Constraint
+		}
+		result = append(result, &fieldModel{Name: param.Name, Type: typeModelOf(constraint)})
+	}
+	return result
+}
+
+func fieldsModel(fields []*Field) []*fieldModel {
+	var result []*fieldModel
+	for _, field := range fields {
+		m := &fieldModel{
+			Name:    field.Name,
+			Pos:     field.pos(),
+			Type:    typeModelOf(field.Type),
+			Comment: commentsModel(field.astComment),
+		}
+		if field.astTag != nil {
+			m.Tag = field.astTag.Value
+			m.TagPos = field.astTag.Pos()
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+func interfaceFieldModel(method *InterfaceField) *funcModel {
+	return &funcModel{
+		Name:    method.Name,
+		Pos:     method.astRoot.Pos(),
+		Doc:     commentsModel(method.astRoot.Doc),
+		Params:  fieldsModel(method.Params),
+		Results: fieldsModel(method.Results),
+	}
+}
+
+func functionModel(f *Function) *funcModel {
+	return &funcModel{
+		Name:    f.Name,
+		File:    f.GoSource.Path,
+		Pos:     f.funcDecl.Pos(),
+		NamePos: f.funcDecl.Name.Pos(),
+		Doc:     commentsModel(f.funcDecl.Doc),
+		Params:  fieldsModel(f.Params),
+		Results: fieldsModel(f.Results),
+	}
+}
+
+func typeModelsOf(typers []Typer) []*typeModel {
+	var result []*typeModel
+	for _, typer := range typers {
+		result = append(result, typeModelOf(typer))
+	}
+	return result
+}
+
+// typeModelOf 其他方式创建的类型（如生成swagger时的BaseType）不会出现在解析结果中，保存为kindNil
+func typeModelOf(typer Typer) *typeModel {
+	switch typer := typer.(type) {
+	case *RawType:
+		return &typeModel{Kind: kindRaw, Name: typer.typeName}
+	case *Struct:
+		return &typeModel{Kind: kindNamed, Pkg: typer.goSource.Pkg.Module, Name: typer.StructName}
+	case *Interface:
+		return &typeModel{Kind: kindNamed, Pkg: typer.GoSource.Pkg.Module, Name: typer.InterfaceName}
+	case *Alias:
+		return &typeModel{Kind: kindNamed, Pkg: typer.Gosourse.Pkg.Module, Name: typer.Name}
+	case *PointerType:
+		return &typeModel{Kind: kindPointer, Elem: typeModelOf(typer.Typer)}
+	case *ArrayType:
+		return &typeModel{Kind: kindArray, Elem: typeModelOf(typer.Typer)}
+	case *MapType:
+		return &typeModel{Kind: kindMap, Key: typeModelOf(typer.KeyTyper), Elem: typeModelOf(typer.ValueTyper)}
+	case *InstanceType:
+		return &typeModel{Kind: kindInstance, Elem: typeModelOf(typer.Typer), Args: typeModelsOf(typer.TypeArgs)}
+	case *TypeParam:
+		return &typeModel{Kind: kindParam, Name: typer.Name, Index: typer.Index}
+	case *AnonymousStruct:
+		return &typeModel{Kind: kindStruct, Fields: fieldsModel(typer.Fields)}
+	case *AnonymousInterface:
+		m := &typeModel{Kind: kindInterface, Args: typeModelsOf(typer.Embeds)}
+		for _, method := range typer.Methods {
+			m.Methods = append(m.Methods, interfaceFieldModel(method))
+		}
+		return m
+	case *FuncType:
+		return &typeModel{Kind: kindFunc, Args: typeModelsOf(typer.Params), Results: typeModelsOf(typer.Results), Variadic: typer.Variadic}
+	case *ChanType:
+		return &typeModel{Kind: kindChan, Dir: typer.Dir, Elem: typeModelOf(typer.Elem)}
+	}
+	return &typeModel{Kind: kindNil}
+}
+
+// parseModel 从缓存的模型建立package，与Parse解析源码的顺序一致：先创建所有类型，再依次解析类型，变量和函数
+func (pkg *Package) parseModel(imports []string, model *packageModel) {
+	for _, file := range model.Files {
+		pkg.fset.AddFile(file.Name, file.Base, file.Size).SetLines(file.Lines)
+	}
+	sources := make(map[string]*Gosourse)
+	for _, source := range model.Sources {
+		goSource := NewGosourse(nil, pkg, source.Path)
+		if source.Imports != nil {
+			goSource.Imports = source.Imports
+		}
+		sources[source.Path] = goSource
+		pkg.goSources = append(pkg.goSources, goSource)
+	}
+	GlobalProject.prefetch(imports)
+	typers := make([]Typer, len(model.Types))
+	for i, decl := range model.Types {
+		spec := &ast.TypeSpec{
+			Name: &ast.Ident{NamePos: decl.Pos, Name: decl.Name},
+			Doc:  commentGroup(decl.Doc),
+		}
+		switch decl.Kind {
+		case declStruct:
+			typers[i] = NewStruct(sources[decl.File], spec)
+		case declInterface:
+			typers[i] = NewInterface(sources[decl.File], spec)
+		default:
+			typers[i] = NewAlias(spec, sources[decl.File], decl.Equal)
+		}
+	}
+	for _, enum := range model.Enums {
+		for _, value := range enum.Values {
+			pkg.Enums[enum.Type] = append(pkg.Enums[enum.Type], &value)
+		}
+	}
+	for _, errorCode := range model.ErrorCodes {
+		pkg.ErrorCodes[errorCode.Name] = &ErrorCode{
+			Name:    errorCode.Name,
+			Comment: errorCode.Comment,
+			Pkg:     pkg,
+			code:    errorCode.Code,
+			message: errorCode.Message,
+		}
+	}
+	for i, decl := range model.Types {
+		goSource := sources[decl.File]
+		switch typer := typers[i].(type) {
+		case *Struct:
+			typer.ParseComment()
+			typer.TypeParameter = typeParamsOfModel(decl.TypeParams, goSource)
+			typer.Fields = fieldsOfModel(decl.Fields, goSource, FieldListToMap(typer.TypeParameter))
+		case *Interface:
+			parseComment(typer.astRoot.Doc, &typer.Comment, goSource)
+			for _, method := range decl.Methods {
+				typer.Methods = append(typer.Methods, interfaceFieldOfModel(method, goSource, nil))
+			}
+		case *Alias:
+			typer.TypeParameter = typeParamsOfModel(decl.TypeParams, goSource)
+			typer.Typer = typerOfModel(decl.Type, goSource, FieldListToMap(typer.TypeParameter))
+		}
+	}
+	for _, v := range model.Vars {
+		goSource := sources[v.File]
+		field := FieldBasic{GoSource: goSource}
+		for _, name := range v.Names {
+			field.astNames = append(field.astNames, &ast.Ident{NamePos: name.Pos, Name: name.Name})
+		}
+		field.Type = typerOfModel(v.Type, goSource, nil)
+		field.parseComment(commentGroup(v.Comment))
+		var comment varComment
+		parseComment(commentGroup(v.Doc), &comment, goSource)
+		for _, name := range v.Names {
+			field1 := VarField{
+				FieldBasic: field,
+				VarComment: comment,
+			}
+			field1.Name = name.Name
+			pkg.GlobalVar[name.Name] = &field1
+		}
+	}
+	for _, fun := range model.Funcs {
+		pkg.parseFuncModel(fun, sources[fun.File])
+	}
+	for _, diagnostic := range model.Diagnostics {
+		Diagnostics.Report(Severity(diagnostic.Severity), diagnostic.Position, "%s", diagnostic.Message)
+	}
+}
+
+func (pkg *Package) parseFuncModel(m *funcModel, goSource *Gosourse) {
+	funcDecl := &ast.FuncDecl{
+		Doc:  commentGroup(m.Doc),
+		Name: &ast.Ident{NamePos: m.NamePos, Name: m.Name},
+		Type: &ast.FuncType{Func: m.Pos},
+	}
+	if !m.Method {
+		f := NewFunction(funcDecl, goSource)
+		f.parseModel(m)
+		pkg.FunctionManager.AddCallable(f)
+		return
+	}
+	method := NewMethod(funcDecl, goSource)
+	method.parseModel(m)
+	if m.Recv != "" {
+		receiver := pkg.GetTyper(m.Recv).(*Struct)
+		method.Receiver = receiver
+		receiver.MethodManager.AddCallable(method)
+	}
+}
+
+func (f *Function) parseModel(m *funcModel) {
+	f.Name = m.Name
+	parseComment(f.funcDecl.Doc, &f.Comment, f.GoSource)
+	f.Params = fieldsOfModel(m.Params, f.GoSource, nil)
+	f.Results = fieldsOfModel(m.Results, f.GoSource, nil)
+}
+
+func commentGroup(comments []commentModel) *ast.CommentGroup {
+	if len(comments) == 0 {
+		return nil
+	}
+	group := &ast.CommentGroup{}
+	for _, comment := range comments {
+		group.List = append(group.List, &ast.Comment{Slash: comment.Slash, Text: comment.Text})
+	}
+	return group
+}
+
+// typeParamsOfModel 与parseTypeParams一致，先创建所有参数再解析约束
+func typeParamsOfModel(params []*fieldModel, goSource *Gosourse) []*Field {
+	var fields []*Field
+	for i, param := range params {
+		fields = append(fields, NewSimpleField(&TypeParam{Name: param.Name, Index: i}, param.Name))
+	}
+	typeMap := FieldListToMap(fields)
+	for i, param := range params {
+		fields[i].Type.(*TypeParam).Constraint = typerOfModel(param.Type, goSource, typeMap)
+	}
+	return fields
+}
+
+func fieldsOfModel(fields []*fieldModel, goSource *Gosourse, typeMap map[string]*Field) []*Field {
+	var result []*Field
+	for _, m := range fields {
+		field := &Field{
+			FieldBasic: FieldBasic{Name: m.Name, GoSource: goSource},
+			Tags:       make(map[string]string),
+		}
+		if m.Name != "" {
+			field.astNames = []*ast.Ident{{NamePos: m.Pos, Name: m.Name}}
+		} else {
+			field.astType = &ast.Ident{NamePos: m.Pos}
+		}
+		if m.Tag != "" {
+			field.astTag = &ast.BasicLit{ValuePos: m.TagPos, Kind: token.STRING, Value: m.Tag}
+			field.parseTag(field.astTag)
+		}
+		field.Type = typerOfModel(m.Type, goSource, typeMap)
+		field.parseComment(commentGroup(m.Comment))
+		result = append(result, field)
+	}
+	return result
+}
+
+func interfaceFieldOfModel(m *funcModel, goSource *Gosourse, typeMap map[string]*Field) *InterfaceField {
+	method := NewInterfaceField(&ast.Field{
+		Doc:   commentGroup(m.Doc),
+		Names: []*ast.Ident{{NamePos: m.Pos, Name: m.Name}},
+	}, goSource)
+	parseComment(method.astRoot.Doc, &method.Comment, goSource)
+	method.Name = m.Name
+	method.Params = fieldsOfModel(m.Params, goSource, typeMap)
+	method.Results = fieldsOfModel(m.Results, goSource, typeMap)
+	return method
+}
+
+func typersOfModel(models []*typeModel, goSource *Gosourse, typeMap map[string]*Field) []Typer {
+	var result []Typer
+	for _, m := range models {
+		result = append(result, typerOfModel(m, goSource, typeMap))
+	}
+	return result
+}
+
+// typerOfModel 与parseType一致，无法解析的类型为nil
+func typerOfModel(m *typeModel, goSource *Gosourse, typeMap map[string]*Field) Typer {
+	if m == nil {
+		return nil
+	}
+	switch m.Kind {
+	case kindRaw:
+		if raw := GetRawType(m.Name); raw != nil {
+			return raw
+		}
+		return &RawType{BaseType: BaseType{typeName: m.Name}}
+	case kindNamed:
+		pkg := goSource.Pkg
+		if m.Pkg != pkg.Module {
+			pkg = GlobalProject.FindPackage(m.Pkg)
+		}
+		return pkg.GetTyper(m.Name)
+	case kindPointer:
+		if elem := typerOfModel(m.Elem, goSource, typeMap); elem != nil {
+			return NewPointerType(elem)
+		}
+	case kindArray:
+		return &ArrayType{Typer: typerOfModel(m.Elem, goSource, typeMap)}
+	case kindMap:
+		return &MapType{
+			BaseType:   BaseType{typeName: "map"},
+			KeyTyper:   typerOfModel(m.Key, goSource, typeMap),
+			ValueTyper: typerOfModel(m.Elem, goSource, typeMap),
+		}
+	case kindInstance:
+		if origin := typerOfModel(m.Elem, goSource, typeMap); origin != nil {
+			return &InstanceType{Typer: origin, TypeArgs: typersOfModel(m.Args, goSource, typeMap)}
+		}
+	case kindParam:
+		if param := typeMap[m.Name]; param != nil {
+			return param.Type
+		}
+		return &TypeParam{Name: m.Name, Index: m.Index}
+	case kindStruct:
+		return &AnonymousStruct{goSource: goSource, Fields: fieldsOfModel(m.Fields, goSource, typeMap)}
+	case kindInterface:
+		iface := &AnonymousInterface{goSource: goSource, Embeds: typersOfModel(m.Args, goSource, typeMap)}
+		for _, method := range m.Methods {
+			iface.Methods = append(iface.Methods, interfaceFieldOfModel(method, goSource, typeMap))
+		}
+		return iface
+	case kindFunc:
+		return &FuncType{
+			Params:   typersOfModel(m.Args, goSource, typeMap),
+			Results:  typersOfModel(m.Results, goSource, typeMap),
+			Variadic: m.Variadic,
+		}
+	case kindChan:
+		return &ChanType{Dir: m.Dir, Elem: typerOfModel(m.Elem, goSource, typeMap)}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package astinfo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// cacheProject a依赖b，b依赖c，d只依赖标准库
+var cacheProject = map[string]string{
+	"go.mod": "module example.com/cachetest\n\ngo 1.23\n",
+	"a/a.go": `package a
+
+import "example.com/cachetest/b"
+
+// A 文档
+type A struct {
+	B b.B ` + "`json:\"b\"`" + `
+}
+`,
+	"b/b.go": `package b
+
+import "example.com/cachetest/c"
+
+type B struct {
+	C *c.C
+}
+`,
+	"c/c.go": `package c
+
+type C struct {
+	Name string
+}
+`,
+	"d/d.go": `package d
+
+import "strings"
+
+type D struct {
+	Builder strings.Builder
+}
+`,
+}
+
+func writeCacheProject(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// parseCacheProject 解析工程，返回从源码解析的package
+func parseCacheProject(t *testing.T, dir string) (*MainProject, []string) {
+	mp := CreateProject(dir, &Config{})
+	if err := mp.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	return mp, mp.parseCache.parsed
+}
+
+// projectPackages 工程中的package，已排序
+func projectPackages(parsed []string) []string {
+	var result []string
+	for _, module := range parsed {
+		if strings.HasPrefix(module, "example.com/cachetest/") {
+			result = append(result, strings.TrimPrefix(module, "example.com/cachetest/"))
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestParseCache(t *testing.T) {
+	resetDiagnostics(t)
+	dir := t.TempDir()
+	writeCacheProject(t, dir, cacheProject)
+
+	_, parsed := parseCacheProject(t, dir)
+	if got := projectPackages(parsed); !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("first run parsed %v, want all packages", got)
+	}
+	if !slices.Contains(parsed, "strings") {
+		t.Fatalf("first run parsed %v, want strings", parsed)
+	}
+
+	// 命中缓存时不解析任何源码，模型与解析源码一致
+	mp, parsed := parseCacheProject(t, dir)
+	if len(parsed) != 0 {
+		t.Fatalf("cached run parsed %v, want none", parsed)
+	}
+	a := mp.FindPackage("example.com/cachetest/a").GetTyper("A").(*Struct)
+	if a.Comment.Doc != "A 文档" {
+		t.Errorf("A doc = %q", a.Comment.Doc)
+	}
+	field := a.Fields[0]
+	if field.Tags["json"] != "b" {
+		t.Errorf("A.B tags = %v", field.Tags)
+	}
+	if pos := field.Position(); pos.Filename != filepath.Join(dir, "a", "a.go") || pos.Line != 7 || pos.Column != 2 {
+		t.Errorf("A.B position = %s", pos)
+	}
+	b, ok := field.Type.(*Struct)
+	if !ok || b.IDName() != "example.com/cachetest/b.B" {
+		t.Fatalf("A.B type = %#v", field.Type)
+	}
+	if got := b.Fields[0].Type.IDName(); got != "example.com/cachetest/c.C" {
+		t.Errorf("B.C type = %s", got)
+	}
+	d := mp.FindPackage("example.com/cachetest/d").GetTyper("D").(*Struct)
+	if got := d.Fields[0].Type.IDName(); got != "strings.Builder" {
+		t.Errorf("D.Builder type = %s", got)
+	}
+
+	// c修改后，c和依赖c的b，a重新解析，d和标准库使用缓存
+	writeCacheProject(t, dir, map[string]string{"c/c.go": `package c
+
+type C struct {
+	Name string
+	Age  int
+}
+`})
+	mp, parsed = parseCacheProject(t, dir)
+	if got := projectPackages(parsed); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("run after change parsed %v, want a, b, c", got)
+	}
+	if slices.Contains(parsed, "strings") {
+		t.Fatalf("run after change parsed %v, strings should be cached", parsed)
+	}
+	c := mp.FindPackage("example.com/cachetest/c").GetTyper("C").(*Struct)
+	if len(c.Fields) != 2 {
+		t.Errorf("C fields = %d, want 2", len(c.Fields))
+	}
+}
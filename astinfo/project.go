@@ -17,6 +17,7 @@ type Project struct {
 	Module  string // 项目模块名称（从go.mod解析）
 	Path    string // 项目根目录的绝对路径
 	Require []*modfile.Require
+	Replace []*modfile.Replace
 }
 
 func (p *Project) ParseModule() error {
@@ -32,6 +33,7 @@ func (p *Project) ParseModule() error {
 	}
 	p.Module = modfile.Module.Mod.Path
 	p.Require = modfile.Require
+	p.Replace = modfile.Replace
 	// TODO
 	// fmt.Printf("Module: %s\n", p.Module)
 	return nil
@@ -73,6 +75,7 @@ func (p *Project) ParseCode() error {
 	//             // method
 	//     }
 	// }
+	var dirs []string
 	err := filepath.WalkDir(p.Path, func(path string, d fs.DirEntry, err error) error {
 		//path是全路径
 		if err != nil {
@@ -80,33 +83,60 @@ func (p *Project) ParseCode() error {
 		}
 		// Skip .git and gen directories
 		if d.IsDir() {
-			dirName := filepath.Base(path)
-			skipdirs := []string{".git", "gen"}
-			for _, skipdir := range skipdirs {
-				if dirName == skipdir {
-					return filepath.SkipDir
-				}
-			}
-
-			if err := p.ParsePackage(path); err != nil {
-				return fmt.Errorf("error parsing package at %s: %w", path, err)
+			if SkipDir(d.Name()) {
+				return filepath.SkipDir
 			}
+			dirs = append(dirs, path)
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	// 先并发加载所有package的文件，再按照目录顺序解析
+	var pkgPaths []string
+	for _, dir := range dirs {
+		pkgPath, err := p.packagePath(dir)
+		if err != nil {
+			return err
+		}
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	GlobalProject.prefetch(pkgPaths)
+	for _, dir := range dirs {
+		if err := p.ParsePackage(dir); err != nil {
+			return fmt.Errorf("error parsing package at %s: %w", dir, err)
+		}
+	}
+	return nil
+}
 
-	return err
+// SkipDir 解析工程和gos watch监控文件时跳过的目录：git目录，gos的缓存目录，生成代码的gen目录和vendor
+func SkipDir(name string) bool {
+	switch name {
+	case ".git", ".gos", "gen", "vendor":
+		return true
+	}
+	return false
 }
 
-// dir是pacakge 所在的全路径
-func (p *Project) ParsePackage(dir string) error {
+// packagePath 目录对应的包全路径
+func (p *Project) packagePath(dir string) (string, error) {
 	// 计算相对路径
 	relPath, err := filepath.Rel(p.Path, dir)
 	if err != nil {
-		return err
+		return "", err
 	}
 	// 用mode+相对路径，得到包全路径
-	pkgPath := filepath.Join(p.Module, relPath)
+	return filepath.Join(p.Module, relPath), nil
+}
+
+// dir是pacakge 所在的全路径
+func (p *Project) ParsePackage(dir string) error {
+	pkgPath, err := p.packagePath(dir)
+	if err != nil {
+		return err
+	}
 	pkg := GlobalProject.FindPackage(pkgPath)
 	pkg.Parse()
 	// pkg.Simple = p.Simple
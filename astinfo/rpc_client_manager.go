@@ -1,6 +1,9 @@
 package astinfo
 
-import "strings"
+import (
+	"path/filepath"
+	"strings"
+)
 
 type RpcClientManager struct {
 	ClientGen map[string]ClientGen
@@ -25,49 +28,55 @@ func (manager *RpcClientManager) registerClientGen(gen ...ClientGen) {
 // Generate
 func (manager *RpcClientManager) Generate(file *GenedFile) error {
 	project := GlobalProject
-	var clients map[string][]*Interface = map[string][]*Interface{}
-	var clientVar = make(map[*Interface]*VarField)
-	for _, pkg := range project.Packages {
-		// for _, iface := range pkg.Interfaces {
-		// 	if iface.Comment.Type == "" {
-		// 		continue
-		// 	}
-		// 	clients[iface.Comment.Type] = append(clients[iface.Comment.Type], iface)
-		// }
-		for _, varField := range pkg.GlobalVar {
+	// key为client类型，value为该类型的全部client变量；同一个interface可以对应多个变量；
+	var clients map[string][]*VarField = map[string][]*VarField{}
+	for _, pkg := range sortedValues(project.Packages) {
+		for _, varField := range sortedValues(pkg.GlobalVar) {
 			if iface, ok := varField.Type.(*Interface); ok {
 				if iface.Comment.Type != "" {
-					clientVar[iface] = varField
-					clients[iface.Comment.Type] = append(clients[iface.Comment.Type], iface)
+					clients[iface.Comment.Type] = append(clients[iface.Comment.Type], varField)
 				}
 			}
 		}
 	}
-	for clientType, ifaces := range clients {
+	// 所有生成了client的变量，用于生成mock；
+	var mockVars []*VarField
+	for _, clientType := range sortedKeys(clients) {
+		clientVars := clients[clientType]
 		gen, ok := manager.ClientGen[clientType]
 		if !ok {
 			continue
 		}
-		file := createGenedFile("rpc_client_" + clientType + ".go")
+		mockVars = append(mockVars, clientVars...)
+		file := createGenedFile("rpc_client_" + clientType)
+		// 旧版本生成的文件名为rpc_client_xxx.go.go，与新文件重复定义
+		GenOutput.Remove(filepath.Join(GlobalProject.genDir(), "rpc_client_"+clientType+".go.go"))
 		var sb strings.Builder
 		gen.GenerateCommon(file)
-		var rpcClientVar = make(map[*Interface]*VarField)
-		for _, iface := range ifaces {
+		// 每个interface仅生成一次实现代码；
+		var genedIface = make(map[*Interface]bool)
+		for _, varField := range clientVars {
+			iface := varField.Type.(*Interface)
+			if genedIface[iface] {
+				continue
+			}
+			genedIface[iface] = true
 			err := gen.Generate(iface, file)
 			if err != nil {
 				return err
 			}
-			if _, ok := clientVar[iface]; ok {
-				rpcClientVar[iface] = clientVar[iface]
-			}
 		}
-		GlobalProject.InitFuncs4Server = append(GlobalProject.InitFuncs4Server, gen.InitClientVariable(rpcClientVar, file))
+		GlobalProject.InitFuncs4Server = append(GlobalProject.InitFuncs4Server, gen.InitClientVariable(clientVars, file))
 		file.AddBuilder(&sb)
 		file.save()
 	}
-	// for iface, varName := range clientVar {
-
-	// }
+	// 没有client变量的类型，删除之前生成的文件
+	for _, clientType := range sortedKeys(manager.ClientGen) {
+		if _, ok := clients[clientType]; !ok {
+			GenOutput.Remove(filepath.Join(GlobalProject.genDir(), "rpc_client_"+clientType+".go"))
+		}
+	}
+	genMockCode(mockVars)
 	return nil
 }
 
@@ -80,6 +89,6 @@ func RegisterClientGen(gen ...ClientGen) {
 type ClientGen interface {
 	GenerateCommon(file *GenedFile)
 	Generate(iface *Interface, file *GenedFile) error
-	InitClientVariable(rpcClientVar map[*Interface]*VarField, file *GenedFile) string // 返回init函数的名字；
-	GetName() string                                                                  // 返回client类型的名字；
+	InitClientVariable(rpcClientVar []*VarField, file *GenedFile) string // 返回init函数的名字；
+	GetName() string                                                     // 返回client类型的名字；
 }
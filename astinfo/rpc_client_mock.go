@@ -0,0 +1,235 @@
+package astinfo
+
+import (
+	"fmt"
+	"go/token"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 为rpc client的interface生成mock实现，保存在gen/mocks中，供测试使用；
+// 每个方法生成
+// 1. XxxFunc 桩函数，为空时返回零值和错误；
+// 2. XxxCalls 调用记录；
+// 3. ExpectXxx，XxxCallCount，AssertXxxCalled 期望辅助函数；
+// 每个client变量生成MockXxx函数，在测试期间将全局变量替换为mock，测试结束后恢复；
+const mockTemplate = `
+{{range .Mocks}}{{$mock := .}}
+// {{.Name}} 是{{.IfaceName}}的mock实现
+type {{.Name}} struct {
+	mu sync.Mutex
+{{range .Methods}}	{{.Name}}Func func({{.Params}}) ({{.Results}})
+	{{.Name}}Calls []{{$mock.Name}}{{.Name}}Call
+{{end}}}
+{{range .Methods}}
+// {{$mock.Name}}{{.Name}}Call 记录一次{{.Name}}调用的参数
+type {{$mock.Name}}{{.Name}}Call struct {
+{{range .Fields}}	{{.}}
+{{end}}}
+
+func (m *{{$mock.Name}}) {{.Name}}({{.Params}}) ({{.NamedResults}}) {
+	m.mu.Lock()
+	m.{{.Name}}Calls = append(m.{{.Name}}Calls, {{$mock.Name}}{{.Name}}Call{ {{.Args}} })
+	fn := m.{{.Name}}Func
+	m.mu.Unlock()
+	if fn == nil {
+		{{.NotStubbed}}
+		return
+	}
+	return fn({{.Args}})
+}
+
+// Expect{{.Name}} 设置{{.Name}}固定返回的结果
+func (m *{{$mock.Name}}) Expect{{.Name}}({{.NamedResults}}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.{{.Name}}Func = func({{.Params}}) ({{.Results}}) {
+		return {{.ResultNames}}
+	}
+}
+
+// {{.Name}}CallCount 返回{{.Name}}被调用的次数
+func (m *{{$mock.Name}}) {{.Name}}CallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.{{.Name}}Calls)
+}
+
+// Assert{{.Name}}Called 检查{{.Name}}被调用的次数
+func (m *{{$mock.Name}}) Assert{{.Name}}Called(t testing.TB, times int) {
+	t.Helper()
+	if count := m.{{.Name}}CallCount(); count != times {
+		t.Errorf("{{$mock.Name}}.{{.Name}} called %d times, expected %d", count, times)
+	}
+}
+{{end}}{{end}}
+{{range .Vars}}
+// Mock{{.Name}} 在测试期间将{{.VarRef}}替换为mock，测试结束后恢复
+func Mock{{.Name}}(t testing.TB) *{{.MockName}} {
+	mock := &{{.MockName}}{}
+	old := {{.VarRef}}
+	{{.VarRef}} = mock
+	t.Cleanup(func() {
+		{{.VarRef}} = old
+	})
+	return mock
+}
+{{end}}`
+
+type mockMethodData struct {
+	Name         string
+	Params       string
+	Results      string
+	NamedResults string
+	ResultNames  string
+	Args         string
+	Fields       []string
+	NotStubbed   string
+}
+
+type mockData struct {
+	Name      string
+	IfaceName string
+	Methods   []*mockMethodData
+}
+
+type mockVarData struct {
+	Name     string
+	MockName string
+	VarRef   string
+}
+
+// mockReservedNames 生成的方法中使用的名字：接收者m，局部变量fn，结果r0，r1...，以及errors包；参数不能与之重名
+var mockReservedNames = map[string]bool{"m": true, "fn": true, "errors": true}
+
+// 参数名可能为空，_，或者与生成代码中使用的名字重名，此时用p0，p1代替；
+// 参数首字母大写后作为Call结构体的字段名，used中记录大写后的名字，避免a和A生成重复的字段；
+func mockParamName(field *Field, index int, used map[string]bool) string {
+	name := field.Name
+	if name == "" || name == "_" || mockReservedNames[name] || used[Capitalize(name)] || isMockResultName(name) {
+		name = "p" + strconv.Itoa(index)
+		for used[Capitalize(name)] {
+			name = "_" + name
+		}
+	}
+	used[Capitalize(name)] = true
+	return name
+}
+
+// isMockResultName 结果统一命名为r0，r1...
+func isMockResultName(name string) bool {
+	if len(name) < 2 || name[0] != 'r' {
+		return false
+	}
+	_, err := strconv.Atoi(name[1:])
+	return err == nil
+}
+
+func newMockMethodData(mockName string, method *InterfaceField, file *GenedFile) (*mockMethodData, error) {
+	data := &mockMethodData{Name: method.Name}
+	var params, args []string
+	used := make(map[string]bool)
+	for i, param := range method.Params {
+		if param.Type == nil {
+			return nil, fmt.Errorf("type of param %d in %s is not supported", i, method.Name)
+		}
+		name := mockParamName(param, i, used)
+		params = append(params, name+" "+param.Type.RefName(file))
+		args = append(args, name)
+		data.Fields = append(data.Fields, Capitalize(name)+" "+param.Type.RefName(file))
+	}
+	var results, namedResults, resultNames []string
+	for i, result := range method.Results {
+		if result.Type == nil {
+			return nil, fmt.Errorf("type of result %d in %s is not supported", i, method.Name)
+		}
+		// 结果统一命名为r0，r1，避免和参数重名；
+		name := "r" + strconv.Itoa(i)
+		typeName := result.Type.RefName(file)
+		results = append(results, typeName)
+		namedResults = append(namedResults, name+" "+typeName)
+		resultNames = append(resultNames, name)
+		if typeName == "error" {
+			file.GetImport(SimplePackage("errors", "errors"))
+			data.NotStubbed = name + ` = errors.New("` + mockName + "." + method.Name + ` is not stubbed")`
+		}
+	}
+	data.Params = strings.Join(params, ", ")
+	data.Args = strings.Join(args, ", ")
+	data.Results = strings.Join(results, ", ")
+	data.NamedResults = strings.Join(namedResults, ", ")
+	data.ResultNames = strings.Join(resultNames, ", ")
+	return data, nil
+}
+
+// genMockCode 为clientVars中的interface生成mock，并为每个变量生成替换函数；
+// 没有需要mock的client时，删除之前生成的mock文件，避免其中引用已经删除的interface导致编译失败；
+func genMockCode(clientVars []*VarField) {
+	file := createSubGenedFile("mocks", "mocks")
+	mockFile := filepath.Join(GlobalProject.genDir(), file.dir, file.name+".go")
+	file.GetImport(SimplePackage("sync", "sync"))
+	file.GetImport(SimplePackage("testing", "testing"))
+	var data struct {
+		Mocks []*mockData
+		Vars  []*mockVarData
+	}
+	var mocks = make(map[*Interface]*mockData)
+	var varNames = make(map[string]bool)
+	for _, varField := range clientVars {
+		iface := varField.Type.(*Interface)
+		mock, ok := mocks[iface]
+		if !ok {
+			mock = &mockData{
+				Name:      iface.InterfaceName + "Mock",
+				IfaceName: iface.RefName(file),
+			}
+			for _, method := range iface.Methods {
+				methodData, err := newMockMethodData(mock.Name, method, file)
+				if err != nil {
+					Warnf(method.GoSource.Position(method.astRoot.Pos()), "skip mock of %s: %s", iface.IDName(), err.Error())
+					mock = nil
+					break
+				}
+				mock.Methods = append(mock.Methods, methodData)
+			}
+			mocks[iface] = mock
+			if mock != nil {
+				data.Mocks = append(data.Mocks, mock)
+			}
+		}
+		if mock == nil {
+			continue
+		}
+		impt := file.GetImport(varField.GoSource.Pkg)
+		name := varField.Name
+		// 不同包中的变量可能重名，此时加上包名；
+		if varNames[name] {
+			name = Capitalize(impt.Name) + name
+		}
+		varNames[name] = true
+		data.Vars = append(data.Vars, &mockVarData{
+			Name:     name,
+			MockName: mock.Name,
+			VarRef:   impt.Name + "." + varField.Name,
+		})
+	}
+	if len(data.Mocks) == 0 {
+		if err := GenOutput.Remove(mockFile); err != nil {
+			Warnf(token.Position{Filename: mockFile}, "remove stale mock file failed: %s", err.Error())
+		}
+		return
+	}
+	tpl, err := template.New("mock").Parse(mockTemplate)
+	if err != nil {
+		log.Fatalf("Failed to parse mock template: %v", err)
+	}
+	var content strings.Builder
+	if err := tpl.Execute(&content, data); err != nil {
+		log.Fatalf("Failed to execute mock template: %v", err)
+	}
+	file.AddBuilder(&content)
+	file.save()
+}
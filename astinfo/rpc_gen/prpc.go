@@ -1,7 +1,6 @@
 package rpcgen
 
 import (
-	"fmt"
 	"log"
 	"strings"
 	"text/template"
@@ -10,6 +9,7 @@ import (
 )
 
 type PrpcGen struct {
+	generated bool // 公共代码只生成一次
 }
 
 func (prpc *PrpcGen) GetName() string {
@@ -50,7 +50,8 @@ func (prpc *PrpcGen) genRpcClientCode(file *astinfo.GenedFile, structName string
     //无论object是否位指针，都需要取地址
     json.Unmarshal(*res.O[1].(*json.RawMessage), &obj)
     {{end}}    return
-}`
+}
+`
 
 	// 准备模板数据
 	data := struct {
@@ -113,13 +114,11 @@ func (prpc *PrpcGen) genRpcClientCode(file *astinfo.GenedFile, structName string
 	file.AddBuilder(&sb)
 }
 
-var generated bool
-
 func (prpc *PrpcGen) GenerateCommon(file *astinfo.GenedFile) {
-	if generated {
+	if prpc.generated {
 		return
 	}
-	generated = true
+	prpc.generated = true
 	file.GetImport(astinfo.SimplePackage("bytes", "bytes"))
 	file.GetImport(astinfo.SimplePackage("encoding/json", "json"))
 	file.GetImport(astinfo.SimplePackage("fmt", "fmt"))
@@ -161,21 +160,27 @@ func (logger *defaultRpcLogger) LogError(_ context.Context, url, err string) {
 
 type RpcClient struct {
 	Prefix    string
+	resolver  Resolver
 	rpcLogger rpcLogger
 }
 
 func (client *RpcClient) SendRequest(ctx context.Context, name string, array []any) RpcResult {
-	url := client.Prefix + name
+	host, err := client.resolver.Resolve(ctx)
+	if err != nil {
+		client.rpcLogger.LogError(ctx, client.Prefix+name, err.Error())
+		return RpcResult{C: 1, O: [2]any{&Error{Message: "resolve host failed"}, &json.RawMessage{}}}
+	}
+	url := host + client.Prefix + name
 	content, marError := json.Marshal(array)
 	if marError != nil {
 		client.rpcLogger.LogError(ctx, url, marError.Error())
 		return RpcResult{C: 1, O: [2]any{nil, &json.RawMessage{}}}
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewReader(content))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(content))
 	var resp *http.Response
 	if err == nil {
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set(TraceId, ctx.Value(TraceIdNameInContext).(string))
+		injectTrace(ctx, req.Header)
 		resp, err = http.DefaultClient.Do(req)
 		client.rpcLogger.LogRequest(ctx, url, string(content))
 	}
@@ -194,19 +199,19 @@ func (client *RpcClient) SendRequest(ctx context.Context, name string, array []a
 	return res
 }
 `)
-	key := astinfo.GlobalProject.Cfg.Generation.TraceKey
-	module := astinfo.GlobalProject.Cfg.Generation.TraceKeyMod
-	if key != "" {
-		// prpc的发送请求是，会向http头添加traceId，需要使用该变量
-		oneImport := file.GetImport(astinfo.SimplePackage(module, "xx"))
-		content.WriteString(fmt.Sprintf("var TraceIdNameInContext = %s.%s{}\n", oneImport.Name, key))
-	} else {
-		content.WriteString("var TraceIdNameInContext = \"badTraceIdName plase config in Generation TraceKeyMod\"\n")
-	}
+	genResolverCode(file, &content)
 	file.AddBuilder(&content)
 }
 
-func (prpc *PrpcGen) InitClientVariable(rpcClientVar map[*astinfo.Interface]*astinfo.VarField, file *astinfo.GenedFile) string {
+// 注释中的值如果不是字符串常量，则认为是pkg中的全局变量，需要加上包名；
+func refValue(value string, pkg *astinfo.Package, file *astinfo.GenedFile) string {
+	if strings.HasPrefix(value, `"`) {
+		return value
+	}
+	return file.GetImport(pkg).Name + "." + value
+}
+
+func (prpc *PrpcGen) InitClientVariable(rpcClientVar []*astinfo.VarField, file *astinfo.GenedFile) string {
 	rpcClientTpl := `
 func initRpcClient() {
 	{{if .HasLogger}}
@@ -218,8 +223,9 @@ func initRpcClient() {
 	{{range .RpcFields}}
 	{{.ImportName}}.{{.FieldName}} = &{{.TypeName}}Struct{
 		client: RpcClient{
-			Prefix: {{.Host}},
-			rpcLogger: &rpclogger,
+			Prefix: {{.Prefix}},
+			resolver: {{.Resolver}},
+			rpcLogger: {{.Logger}},
 		},
 	}
 	{{end}}
@@ -231,7 +237,9 @@ func initRpcClient() {
 		ImportName string
 		FieldName  string
 		TypeName   string
-		Host       string
+		Resolver   string
+		Prefix     string
+		Logger     string
 	}
 
 	data := struct {
@@ -249,20 +257,33 @@ func initRpcClient() {
 		data.LoggerKey = generationCfg.RpcLoggerKey
 	}
 
-	for iface, field := range rpcClientVar {
-		_ = field
-		impt := file.GetImport(iface.GoSource.Pkg)
-		host := iface.Comment.Host
-
-		if !strings.HasPrefix(host, `"`) {
-			host = impt.Name + "." + host
+	for _, field := range rpcClientVar {
+		iface := field.Type.(*astinfo.Interface)
+		varPkg := field.GoSource.Pkg
+		// 变量上的host优先，否则使用interface上的host；两者的变量分别在各自的包中寻找；
+		host, hostPkg := field.VarComment.Host, varPkg
+		if host == "" {
+			host, hostPkg = iface.Comment.Host, iface.GoSource.Pkg
+		}
+		if !strings.HasPrefix(strings.Trim(host, `"`), discoveryPrefix) {
+			host = refValue(host, hostPkg, file)
+		}
+		prefix := `""`
+		if field.VarComment.Prefix != "" {
+			prefix = refValue(field.VarComment.Prefix, varPkg, file)
+		}
+		logger := "&rpclogger"
+		if field.VarComment.Logger != "" {
+			logger = refValue(field.VarComment.Logger, varPkg, file)
 		}
 
 		data.RpcFields = append(data.RpcFields, RpcFieldData{
-			ImportName: impt.Name,
-			FieldName:  "JsinternalClient",
+			ImportName: file.GetImport(varPkg).Name,
+			FieldName:  field.Name,
 			TypeName:   iface.InterfaceName,
-			Host:       host,
+			Resolver:   resolverValue(host),
+			Prefix:     prefix,
+			Logger:     logger,
 		})
 	}
 
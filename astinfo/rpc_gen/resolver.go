@@ -0,0 +1,218 @@
+package rpcgen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/wanjm/gos/astinfo"
+)
+
+// host=discovery:name 表示该client的地址由名为name的Resolver在每次请求时提供；
+const discoveryPrefix = "discovery:"
+
+// 生成代码中的服务发现部分；
+// 1. Resolver接口，每次请求时获取服务地址；
+// 2. StaticResolver，固定地址列表轮询，字符串和变量形式的host都使用它；
+// 3. SrvResolver，通过dns srv记录获取地址，并定时刷新；
+// 4. FileRegistry，从本地json文件读取地址，文件修改后自动重新加载，便于离线测试；
+const resolverCode = `
+// Resolver 在每次rpc请求时返回服务地址，如 http://127.0.0.1:8080
+type Resolver interface {
+	Resolve(ctx context.Context) (string, error)
+}
+
+var resolvers sync.Map
+
+// RegisterResolver 注册服务发现，注释为 host=discovery:name 的client通过name使用该Resolver；
+// 可以在Run之后注册，client在请求时才会查找；
+func RegisterResolver(name string, resolver Resolver) {
+	resolvers.Store(name, resolver)
+}
+
+// 未注册的name，会在环境变量GOS_REGISTRY_FILE指定的文件中寻找；
+var defaultRegistry *FileRegistry
+
+func init() {
+	if path := os.Getenv("GOS_REGISTRY_FILE"); path != "" {
+		defaultRegistry = NewFileRegistry(path)
+	}
+}
+
+type discoveryResolver struct {
+	name string
+}
+
+func (r *discoveryResolver) Resolve(ctx context.Context) (string, error) {
+	if resolver, ok := resolvers.Load(r.name); ok {
+		return resolver.(Resolver).Resolve(ctx)
+	}
+	if defaultRegistry != nil {
+		return defaultRegistry.resolve(ctx, r.name)
+	}
+	return "", fmt.Errorf("no resolver registered for %s", r.name)
+}
+
+// StaticResolver 在固定的地址列表中轮询
+type StaticResolver struct {
+	hosts []string
+	next  atomic.Uint64
+}
+
+func NewStaticResolver(hosts ...string) *StaticResolver {
+	return &StaticResolver{hosts: hosts}
+}
+
+func (r *StaticResolver) Resolve(_ context.Context) (string, error) {
+	if len(r.hosts) == 0 {
+		return "", errors.New("no host available")
+	}
+	index := r.next.Add(1) - 1
+	return r.hosts[index%uint64(len(r.hosts))], nil
+}
+
+// SrvResolver 通过dns srv记录获取服务地址，每TTL刷新一次；刷新失败时继续使用上次的结果；
+type SrvResolver struct {
+	Service string
+	Proto   string
+	Name    string
+	Scheme  string
+	TTL     time.Duration
+	mu      sync.Mutex
+	expire  time.Time
+	static  *StaticResolver
+}
+
+func NewSrvResolver(service, proto, name, scheme string) *SrvResolver {
+	return &SrvResolver{
+		Service: service,
+		Proto:   proto,
+		Name:    name,
+		Scheme:  scheme,
+		TTL:     30 * time.Second,
+	}
+}
+
+func (r *SrvResolver) Resolve(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	if r.static == nil || time.Now().After(r.expire) {
+		_, addrs, err := net.DefaultResolver.LookupSRV(ctx, r.Service, r.Proto, r.Name)
+		if err != nil {
+			if r.static == nil {
+				r.mu.Unlock()
+				return "", err
+			}
+		} else {
+			hosts := make([]string, 0, len(addrs))
+			for _, addr := range addrs {
+				hosts = append(hosts, r.Scheme+"://"+strings.TrimSuffix(addr.Target, ".")+":"+strconv.Itoa(int(addr.Port)))
+			}
+			r.static = NewStaticResolver(hosts...)
+			r.expire = time.Now().Add(r.TTL)
+		}
+	}
+	static := r.static
+	r.mu.Unlock()
+	return static.Resolve(ctx)
+}
+
+// FileRegistry 从本地json文件读取服务地址，格式为 {"name": ["http://host1", "http://host2"]}；
+// 每次请求时检查文件修改时间，修改后重新加载；
+type FileRegistry struct {
+	path     string
+	mu       sync.Mutex
+	modTime  time.Time
+	services map[string]*StaticResolver
+}
+
+func NewFileRegistry(path string) *FileRegistry {
+	return &FileRegistry{path: path}
+}
+
+// Resolver 返回文件中name对应的Resolver
+func (r *FileRegistry) Resolver(name string) Resolver {
+	return &fileResolver{registry: r, name: name}
+}
+
+// Register 将names注册到RegisterResolver中
+func (r *FileRegistry) Register(names ...string) {
+	for _, name := range names {
+		RegisterResolver(name, r.Resolver(name))
+	}
+}
+
+func (r *FileRegistry) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	if r.services != nil && info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	var hosts map[string][]string
+	if err := json.Unmarshal(content, &hosts); err != nil {
+		return fmt.Errorf("parse %s failed: %w", r.path, err)
+	}
+	services := make(map[string]*StaticResolver, len(hosts))
+	for name, list := range hosts {
+		services[name] = NewStaticResolver(list...)
+	}
+	r.services = services
+	r.modTime = info.ModTime()
+	return nil
+}
+
+func (r *FileRegistry) resolve(ctx context.Context, name string) (string, error) {
+	r.mu.Lock()
+	err := r.load()
+	service := r.services[name]
+	r.mu.Unlock()
+	if service == nil {
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("service %s not found in %s", name, r.path)
+	}
+	return service.Resolve(ctx)
+}
+
+type fileResolver struct {
+	registry *FileRegistry
+	name     string
+}
+
+func (r *fileResolver) Resolve(ctx context.Context) (string, error) {
+	return r.registry.resolve(ctx, r.name)
+}
+`
+
+func genResolverCode(file *astinfo.GenedFile, content *strings.Builder) {
+	for _, pkg := range []string{"context", "errors", "fmt", "net", "os", "strconv", "strings", "sync", "sync/atomic", "time", "encoding/json"} {
+		file.GetImport(astinfo.SimplePackage(pkg, pkg[strings.LastIndex(pkg, "/")+1:]))
+	}
+	content.WriteString(resolverCode)
+}
+
+// 生成client的Resolver代码；discovery:name使用注册的Resolver，其他host使用StaticResolver；
+// 字符串常量中可以用逗号分隔多个host，如"http://a,http://b"，生成NewStaticResolver("http://a", "http://b")轮询；
+// host为变量时，变量的值作为一个host；
+func resolverValue(host string) string {
+	name := strings.Trim(host, `"`)
+	if strings.HasPrefix(name, discoveryPrefix) {
+		return `&discoveryResolver{name: "` + name[len(discoveryPrefix):] + `"}`
+	}
+	value, err := strconv.Unquote(host)
+	if err != nil {
+		return "NewStaticResolver(" + host + ")"
+	}
+	var hosts []string
+	for _, h := range strings.Split(value, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, strconv.Quote(h))
+		}
+	}
+	return "NewStaticResolver(" + strings.Join(hosts, ", ") + ")"
+}
@@ -0,0 +1,22 @@
+package rpcgen
+
+import "testing"
+
+func TestResolverValue(t *testing.T) {
+	for _, tt := range []struct {
+		host string
+		want string
+	}{
+		{`"http://a"`, `NewStaticResolver("http://a")`},
+		{`"http://a,http://b"`, `NewStaticResolver("http://a", "http://b")`},
+		{`" http://a , http://b ,"`, `NewStaticResolver("http://a", "http://b")`},
+		{`"discovery:user"`, `&discoveryResolver{name: "user"}`},
+		{`discovery:user`, `&discoveryResolver{name: "user"}`},
+		{`DefaultHost`, `NewStaticResolver(DefaultHost)`},
+		{`config.Host`, `NewStaticResolver(config.Host)`},
+	} {
+		if got := resolverValue(tt.host); got != tt.want {
+			t.Errorf("resolverValue(%s) = %s, want %s", tt.host, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,11 @@
 package astinfo
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"maps"
-	"net/http"
-	"strconv"
+	"go/ast"
+	"go/token"
+	"path"
 	"strings"
 
 	"github.com/go-openapi/spec"
@@ -17,68 +15,141 @@ type SchemaType interface {
 	InitSchema(*spec.Schema, *Swagger)
 }
 
+// enum中每个值对应的常量名，swagger-ui和代码生成工具用它显示或生成常量
+const enumNamesExtension = "x-enum-varnames"
+
+// 部分类型在json中有固定的表达方式，不按照其定义生成schema；key为类型的IDName；
+var wellKnownSchemas = map[string]func(*spec.Schema){
+	"time.Time": func(schema *spec.Schema) {
+		schema.Typed("string", "date-time")
+	},
+	// json.RawMessage可以是任意json值
+	"encoding/json.RawMessage": func(schema *spec.Schema) {},
+}
+
+// initTypeSchema 根据typer填写schema；无法识别的类型不设置type，表示任意值；
+func initTypeSchema(typer Typer, schema *spec.Schema, swagger *Swagger) {
+	if typer == nil {
+		return
+	}
+	if st, ok := GetBasicType(typer).(SchemaType); ok {
+		st.InitSchema(schema, swagger)
+	}
+}
+
 func (r *RawType) InitSchema(schema *spec.Schema, swagger *Swagger) {
-	// 获取原始类型对应到swagger的类型
-	var name = "integer"
+	// 获取原始类型对应到swagger的类型和格式；any等无法确定的类型不设置type，表示任意值；
 	switch r.typeName {
-	case "string":
-		name = "string"
-	case "array":
-		name = "array"
-	case "map":
-		name = "object"
+	case "string", "error":
+		schema.Typed("string", "")
 	case "bool":
-		name = "bool"
-	case "float32", "float64":
-		name = "number"
+		schema.Typed("boolean", "")
+	case "int8", "int16", "int32", "rune", "byte", "uint8", "uint16":
+		schema.Typed("integer", "int32")
+	case "int", "int64", "uint", "uint32", "uint64", "uintptr":
+		schema.Typed("integer", "int64")
+	case "float32":
+		schema.Typed("number", "float")
+	case "float64":
+		schema.Typed("number", "double")
 	}
-	schema.Type = []string{name}
 }
 
 func (r *ArrayType) InitSchema(schema *spec.Schema, swagger *Swagger) {
+	// []byte在json中为base64编码的字符串
+	if raw, ok := r.Typer.(*RawType); ok && (raw.typeName == "byte" || raw.typeName == "uint8") {
+		schema.Typed("string", "byte")
+		return
+	}
 	schema.Type = []string{"array"}
 	schema.Items = &spec.SchemaOrArray{
 		Schema: &spec.Schema{},
 	}
-	// if r.class == nil {
-	// 	r.class = r.pkg.getStruct(r.typeName, false)
-	// }
-	basicType := GetBasicType(r.Typer)
-	basicType.(SchemaType).InitSchema(schema.Items.Schema, swagger)
+	initTypeSchema(r.Typer, schema.Items.Schema, swagger)
 }
 
-// func (m *MapType) InitSchema(schema *spec.Schema, swagger *Swagger) {
-// 	schema.Type = []string{"object"}
-// 	schema.AdditionalProperties = &spec.SchemaOrBool{
-// 		Schema: &spec.Schema{},
-// 	}
-// }
+func (m *MapType) InitSchema(schema *spec.Schema, swagger *Swagger) {
+	schema.Type = []string{"object"}
+	value := spec.Schema{}
+	initTypeSchema(m.ValueTyper, &value, swagger)
+	schema.AdditionalProperties = &spec.SchemaOrBool{
+		Allows: true,
+		Schema: &value,
+	}
+}
 
 func (s *Struct) InitSchema(schema *spec.Schema, swagger *Swagger) {
-	// schema.Ref = spec.Ref{
-	if s.ref == nil {
-		s.ref = swagger.getRefOfStruct(s)
+	if s.goSource != nil {
+		if init, ok := wellKnownSchemas[s.IDName()]; ok {
+			init(schema)
+			return
+		}
+	}
+	schema.Ref = *swagger.getRefOfStruct(s)
+}
+
+// 匿名结构体直接展开为object，不放入definitions
+func (s *AnonymousStruct) InitSchema(schema *spec.Schema, swagger *Swagger) {
+	schema.Typed("object", "")
+	swagger.addFieldsToSchema("struct", s.Fields, schema)
+}
+
+// 范型结构体的实例化生成单独的definition，如other.Page-api.Req；范型别名使用替换后的类型
+func (t *InstanceType) InitSchema(schema *spec.Schema, swagger *Swagger) {
+	switch origin := t.Typer.(type) {
+	case *Struct:
+		schema.Ref = *swagger.getRefOfInstance(t, origin)
+	case *Alias:
+		initTypeSchema(t.bind(origin.Typer), schema, swagger)
+	default:
+		initTypeSchema(origin, schema, swagger)
 	}
-	schema.Ref = *s.ref
 }
+
+// 未实例化的范型参数使用其约束的schema
+func (p *TypeParam) InitSchema(schema *spec.Schema, swagger *Swagger) {
+	initTypeSchema(p.Constraint, schema, swagger)
+}
+
+// Alias 使用原始类型的schema，如果定义了该类型的常量，则作为enum；
 func (s *Alias) InitSchema(schema *spec.Schema, swagger *Swagger) {
-	// schema.Ref = spec.Ref{
-	basicType := GetBasicType(s.Typer)
-	basicType.(SchemaType).InitSchema(schema, swagger)
+	if init, ok := wellKnownSchemas[s.IDName()]; ok {
+		init(schema)
+		return
+	}
+	initTypeSchema(s.Typer, schema, swagger)
+	enums := s.Gosourse.Pkg.Enums[s.Name]
+	if len(enums) == 0 {
+		return
+	}
+	var names []string
+	for _, enum := range enums {
+		schema.Enum = append(schema.Enum, enum.Value)
+		names = append(names, enum.Name)
+	}
+	schema.AddExtension(enumNamesExtension, names)
+}
+
+// interface的内容在运行时才能确定，表示任意值；
+func (i *Interface) InitSchema(schema *spec.Schema, swagger *Swagger) {
 }
 
 func (e *BaseType) InitSchema(schema *spec.Schema, swagger *Swagger) {
 }
 func (p *PointerType) InitSchema(schema *spec.Schema, swagger *Swagger) {
-	basicType := GetBasicType(p.Typer)
-	basicType.(SchemaType).InitSchema(schema, swagger)
+	initTypeSchema(p.Typer, schema, swagger)
 }
 
 type Swagger struct {
-	swag    *spec.Swagger
-	project *MainProject
-	// definitions    map[*Struct]*spec.Ref
-	responseResult *Struct
+	swag            *spec.Swagger
+	project         *MainProject
+	definitions     map[*Struct]*spec.Ref // 已经生成definition的结构体，每个Swagger单独记录，以便按group生成多份文档
+	definitionOwner map[string]string     // key为definitions中的名字，value为结构体的IDName，用于检查重名
+	instances       map[string]*spec.Ref  // 已经生成definition的范型实例化，key为IDName
+	tags            map[string]*spec.Tag
+	tagGroups       map[string][]string     // key为server的group，value为其中的tag
+	errorServlets   map[*ErrorCode][]string // 本文档中引用错误码的接口，如 POST /user/get；每次生成文档单独记录，不修改ErrorCode
+	responseResult  *Struct
 }
 
 func NewSwagger(project *MainProject) (result *Swagger) {
@@ -100,6 +171,7 @@ func NewSwagger(project *MainProject) (result *Swagger) {
 					Extensions: nil,
 				},
 			},
+			Produces:            []string{"application/json"},
 			Definitions:         make(map[string]spec.Schema),
 			SecurityDefinitions: make(map[string]*spec.SecurityScheme),
 		},
@@ -108,9 +180,14 @@ func NewSwagger(project *MainProject) (result *Swagger) {
 		},
 	}
 	result = &Swagger{
-		swag:    swag,
-		project: project,
-		// definitions: make(map[string]*spec.Ref),
+		swag:            swag,
+		project:         project,
+		definitions:     make(map[*Struct]*spec.Ref),
+		instances:       make(map[string]*spec.Ref),
+		definitionOwner: make(map[string]string),
+		tags:            make(map[string]*spec.Tag),
+		tagGroups:       make(map[string][]string),
+		errorServlets:   make(map[*ErrorCode][]string),
 	}
 
 	if len(project.Cfg.SwaggerCfg.UrlPrefix) > 0 {
@@ -152,47 +229,229 @@ func initOperation(title string) *spec.Operation {
 		},
 	}
 }
+
+// setOperation 根据http方法将operation放到pathItem中
+func setOperation(pathItem *spec.PathItem, method string, operation *spec.Operation) bool {
+	switch method {
+	case POST:
+		pathItem.Post = operation
+	case GET:
+		pathItem.Get = operation
+	case PUT:
+		pathItem.Put = operation
+	case DELETE:
+		pathItem.Delete = operation
+	case PATCH:
+		pathItem.Patch = operation
+	case OPTIONS:
+		pathItem.Options = operation
+	case HEAD:
+		pathItem.Head = operation
+	default:
+		return false
+	}
+	return true
+}
+
+// 没有请求体的方法，request的字段作为query参数
+func hasRequestBody(method string) bool {
+	switch method {
+	case GET, DELETE, HEAD, OPTIONS:
+		return false
+	}
+	return true
+}
+
+// swaggerPath 将gin的 /user/:id/*path 转换为swagger的 /user/{id}/{path}，同时返回路径参数名
+func swaggerPath(url string) (string, []string) {
+	var names []string
+	segments := strings.Split(url, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), names
+}
+
+// 非body参数只支持简单类型和简单类型的数组，从schema中取出type，format，items
+func (swagger *Swagger) simpleParameter(name, in string, field *Field) (spec.Parameter, bool) {
+	param := spec.Parameter{
+		ParamProps: spec.ParamProps{
+			Name:        name,
+			In:          in,
+			Description: field.Comment.comment,
+		},
+	}
+	st, ok := GetBasicType(field.Type).(SchemaType)
+	if !ok {
+		return param, false
+	}
+	schema := spec.Schema{}
+	st.InitSchema(&schema, swagger)
+	if schema.Ref.String() != "" || len(schema.Type) == 0 || schema.Type.Contains("object") {
+		return param, false
+	}
+	param.Type = schema.Type[0]
+	param.Format = schema.Format
+	param.Enum = schema.Enum
+	if schema.Items != nil && schema.Items.Schema != nil {
+		items := schema.Items.Schema
+		if items.Ref.String() != "" || len(items.Type) == 0 {
+			return param, false
+		}
+		param.Items = spec.NewItems().Typed(items.Type[0], items.Format)
+		param.CollectionFormat = "multi"
+	}
+	return param, true
+}
+
+// tag的值可能带有,omitempty等选项，仅取名字部分；
+func tagName(field *Field, tag string) string {
+	name, _, _ := strings.Cut(field.Tags[tag], ",")
+	return name
+}
+
+// requestParameters 生成request结构体对应的参数；
+// 1. 路径参数，对应gin中 c.Param 赋值的字段；
+// 2. header，cookie tag的字段；
+// 3. 有请求体的方法，request作为body，否则其他字段作为query参数（gin使用form tag）；
+func (swagger *Swagger) requestParameters(class Typer, method string, pathNames []string) []spec.Parameter {
+	var parameters []spec.Parameter
+	var bound = make(map[*Field]bool)
+	fields := StructFields(class)
+	for _, name := range pathNames {
+		param := spec.Parameter{
+			ParamProps: spec.ParamProps{Name: name, In: "path", Required: true},
+			SimpleSchema: spec.SimpleSchema{
+				Type: "string",
+			},
+		}
+		for _, field := range fields {
+			if field.Name == Capitalize(name) {
+				bound[field] = true
+				if p, ok := swagger.simpleParameter(name, "path", field); ok {
+					param = p
+					param.Required = true
+				}
+			}
+		}
+		parameters = append(parameters, param)
+	}
+	for _, field := range TaggedFields(fields, "header") {
+		bound[field] = true
+		if param, ok := swagger.simpleParameter(tagName(field, "header"), "header", field); ok {
+			parameters = append(parameters, param)
+		}
+	}
+	// 2.0不支持cookie参数，同一个参数名只能出现一次，所有cookie合并为一个Cookie头；
+	// cookie名记录在x-cookie中，转换为3.1时还原为cookie参数
+	var cookies []interface{}
+	var cookieDocs []string
+	for _, field := range TaggedFields(fields, "cookie") {
+		bound[field] = true
+		// 与生成的代码一致，只支持string类型的cookie
+		if field.Type.RefName(nil) != "string" {
+			continue
+		}
+		name := tagName(field, "cookie")
+		cookies = append(cookies, name)
+		if comment := strings.TrimSpace(field.Comment.comment); comment != "" {
+			name += ": " + comment
+		}
+		cookieDocs = append(cookieDocs, name)
+	}
+	if len(cookies) > 0 {
+		param := *spec.HeaderParam("Cookie").Typed("string", "")
+		param.Required = false
+		param.Description = "cookie " + strings.Join(cookieDocs, "; ")
+		param.AddExtension(cookieExtension, cookies)
+		parameters = append(parameters, param)
+	}
+	if hasRequestBody(method) {
+		schema := spec.Schema{}
+		initTypeSchema(class, &schema, swagger)
+		parameters = append(parameters, spec.Parameter{
+			ParamProps: spec.ParamProps{
+				Name:     "body",
+				In:       "body",
+				Required: true,
+				Schema:   &schema,
+			},
+		})
+		return parameters
+	}
+	var addQuery func(fields []*Field)
+	addQuery = func(fields []*Field) {
+		for _, field := range fields {
+			if field.Name == "" {
+				addQuery(StructFields(GetBasicType(field.Type)))
+				continue
+			}
+			name := tagName(field, "form")
+			if bound[field] || name == "-" || !ast.IsExported(field.Name) {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if param, ok := swagger.simpleParameter(name, "query", field); ok {
+				parameters = append(parameters, param)
+			} else {
+				Warnf(field.Position(), "skip query parameter %s of %s, only simple type is supported", field.Name, class.IDName())
+			}
+		}
+	}
+	addQuery(fields)
+	return parameters
+}
+
 func (swagger *Swagger) addServletFromFunctionManager(pkg *MethodManager) {
 	paths := swagger.swag.Paths.Paths
 	for _, servlet := range pkg.Server {
 		comment := servlet.Comment
 		var url = strings.Trim(comment.Url, "\"")
 		if len(url) == 0 {
-			fmt.Printf("servlet %s has no url\n", servlet.Name)
+			Warnf(servlet.Position(), "servlet %s has no url", servlet.Name)
 			continue
 		}
-		pathItem := spec.PathItem{}
+		// 跟路由的生成保持一致，加上struct上定义的url
+		url, pathNames := swaggerPath(path.Join(servlet.Receiver.Comment.Url, url))
+		key := swagger.project.Cfg.SwaggerCfg.UrlPrefix + url
+		pathItem := paths[key]
 		operation := initOperation(comment.title)
-		var parameter []spec.Parameter
-		switch comment.Method {
-		case POST, "":
-			pathItem.Post = operation
-			var props spec.SchemaProps
-			_ = props
-			if len(servlet.Params) > 1 && servlet.Params[1].Type != nil {
-				t := GetBasicType(servlet.Params[1].Type)
-				ref := swagger.getRefOfStruct(t.(*Struct))
-				parameter = append(parameter, spec.Parameter{
-					ParamProps: spec.ParamProps{
-						Name:     "body",
-						In:       "body",
-						Required: true,
-						Schema: &spec.Schema{
-							SchemaProps: spec.SchemaProps{
-								Ref: *ref,
-							},
-						},
-					},
-				})
-			}
-
-		case GET:
-			pathItem.Get = operation
-		default:
-			fmt.Printf("servlet %s has invalid method %s,which is not supported\n", servlet.Name, servlet.Comment.Method)
+		operation.Tags = swagger.operationTags(servlet)
+		method := comment.Method
+		if method == "" {
+			method = POST
+		}
+		if !setOperation(&pathItem, method, operation) {
+			Errorf(servlet.Position(), "servlet %s has invalid method %s, which is not supported", servlet.Name, servlet.Comment.Method)
 			continue
 		}
-		operation.Parameters = parameter
+		var class Typer
+		if len(servlet.Params) > 1 && servlet.Params[1].Type != nil {
+			switch request := GetBasicType(servlet.Params[1].Type).(type) {
+			case *Struct, *AnonymousStruct:
+				class = request
+			case *InstanceType:
+				if _, ok := request.Typer.(*Struct); ok {
+					class = request
+				}
+			}
+		}
+		if class != nil {
+			operation.Parameters = swagger.requestParameters(class, method, pathNames)
+			if hasRequestBody(method) {
+				// gin的ShouldBind根据Content-Type选择解析方式，json和表单都支持
+				operation.Consumes = []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"}
+			}
+		} else {
+			for _, name := range pathNames {
+				operation.Parameters = append(operation.Parameters, *spec.PathParam(name).Typed("string", ""))
+			}
+		}
 		var objFieldPtr *Field
 		if len(servlet.Results) > 1 {
 			field0 := servlet.Results[0]
@@ -201,91 +460,86 @@ func (swagger *Swagger) addServletFromFunctionManager(pkg *MethodManager) {
 			// }
 			objFieldPtr = field0
 		}
-		// addSecurity(servlet, operation) //apix中使用了全局的header，暂时不显示
+		swagger.addSecurity(servlet, operation)
 		var response spec.Response = swagger.getSwaggerResponse(objFieldPtr)
+		swagger.addErrorCodes(servlet, operation, &response, method+" "+key)
 		operation.Responses.StatusCodeResponses[200] = response
-		paths[swagger.project.Cfg.SwaggerCfg.UrlPrefix+url] = pathItem
-	}
-}
-
-//	func addSecurity(function *Function, operation *spec.Operation) {
-//		// for _, header := range function.comment.security {
-//		// 	operation.Security = append(operation.Security, map[string][]string{
-//		// 		header: {"string"},
-//		// 	})
-//		// }
-//		for _, s := range function.pkg.Project.servers {
-//			if s.name == function.comment.serverName {
-//				for _, filter := range s.urlFilters {
-//					url := filter.url
-//					filterFunction := filter.function
-//					servletUrl := filterFunction.comment.Url
-//					if strings.Contains(servletUrl, url) {
-//						for _, header := range filterFunction.comment.security {
-//							operation.Security = append(operation.Security, map[string][]string{
-//								header: {"string"},
-//							})
-//						}
-//					}
-//				}
-//			}
-//		}
-//	}
-func (swagger *Swagger) GenerateCode(cfg *SwaggerCfg) string {
+		paths[key] = pathItem
+	}
+}
 
+// build 根据解析结果生成文档；group不为空时，只包含该group的servlet
+func (swagger *Swagger) build(group string) {
 	project := swagger.project
-	for name, pkg := range project.Packages {
-		_ = name
-		swagger.addServletFromPackage(pkg)
+	for _, pkg := range sortedValues(project.Packages) {
+		swagger.addServletFromPackage(pkg, group)
 	}
-	swaggerJson, err := swagger.swag.MarshalJSON()
+	swagger.addTags()
+}
+
+// GenerateCode 生成文档，保存后发布到配置的目标；发布失败时返回错误
+// SplitByGroup时，每个包含servlet的group额外生成一份只有该group接口的文档；
+func (swagger *Swagger) GenerateCode(cfg *SwaggerCfg) error {
+	swagger.build("")
+	swaggerJson, err := swagger.marshal(cfg)
 	if err != nil {
-		fmt.Printf("json.Marshal(s.SwaggerProps) error: %v", err)
-		return ""
-	}
-	if cfg.Token == "" {
-		//如果不上传，则打印到控制台
-		fmt.Printf("swagger:%s\n", string(swaggerJson))
-		return ""
-	}
-	cmdMap := map[string]any{
-		"input": string(swaggerJson),
-		"options": map[string]any{
-			"targetEndpointFolderId":        cfg.ServletFolder,
-			"targetSchemaFolderId":          cfg.SchemaFolder,
-			"endpointOverwriteBehavior":     "OVERWRITE_EXISTING",
-			"schemaOverwriteBehavior":       "OVERWRITE_EXISTING",
-			"updateFolderOfChangedEndpoint": false,
-			"prependBasePath":               false,
-		},
+		return fmt.Errorf("marshal swagger failed: %w", err)
 	}
-	data, _ := json.Marshal(cmdMap)
-	url := "http://api.apifox.com/v1/projects/" + strconv.Itoa(cfg.ProjectId) + "/import-openapi?locale=zh-CN"
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(data))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Apifox-Api-Version", "2024-03-28")
-	req.Header.Set("Authorization", "Bearer "+cfg.Token)
-	req.Header.Set("User-Agent", "Apifox/1.0.0 (https://apifox.com)")
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		fmt.Printf("error:%v\n", err)
+	if err := swagger.saveSwagger(swaggerJson, cfg, ""); err != nil {
+		Errorf(token.Position{}, "save swagger failed: %v", err)
+	}
+	if err := swagger.saveErrorCatalog(cfg); err != nil {
+		Errorf(token.Position{}, "save error catalog failed: %v", err)
+	}
+	if cfg.SplitByGroup {
+		for _, group := range swagger.project.servletGroups() {
+			groupSwagger := NewSwagger(swagger.project)
+			groupSwagger.build(group)
+			groupJson, err := groupSwagger.marshal(cfg)
+			if err != nil {
+				return fmt.Errorf("marshal swagger of group %s failed: %w", group, err)
+			}
+			if err := groupSwagger.saveSwagger(groupJson, cfg, group); err != nil {
+				Errorf(token.Position{}, "save swagger of group %s failed: %v", group, err)
+			}
+		}
+	}
+	if swagger.project.Cfg.NoPublish {
+		return nil
+	}
+	return swagger.publish(swaggerJson, cfg)
+}
+
+// marshal 按照cfg.Format生成文档
+func (swagger *Swagger) marshal(cfg *SwaggerCfg) ([]byte, error) {
+	switch cfg.Format {
+	case FormatOpenAPI31:
+		return json.Marshal(convertToOpenAPI31(swagger.swag, cfg.UrlPrefix))
+	case FormatSwagger2, "":
+		return swagger.swag.MarshalJSON()
+	default:
+		return nil, fmt.Errorf("unknown swagger format %s", cfg.Format)
 	}
-	content, _ := io.ReadAll(response.Body)
-	fmt.Printf("response:%v\n", string(content))
-	// fmt.Printf("swagger:%s\n", cmdMap["input"])
-	return (string(data))
 }
-func (swagger *Swagger) addServletFromPackage(pkg *Package) {
+
+func (swagger *Swagger) addServletFromPackage(pkg *Package, group string) {
 	// swagger.addServletFromFunctionManager(&pkg.FunctionManager)
-	for _, class := range pkg.Structs {
-		if class.Comment.serverType == Servlet {
+	for _, class := range sortedValues(pkg.Structs) {
+		if class.Comment.serverType == Servlet && (group == "" || class.Comment.GroupName == group) {
 			swagger.addServletFromFunctionManager(&class.MethodManager)
 		}
 	}
 }
 
-func (swagger *Swagger) addStructFieldsToSchema(class *Struct) map[string]spec.Schema {
-	schemas := make(map[string]spec.Schema)
+// addStructFieldsToSchema 将结构体的字段添加到schema的properties中；
+// 匿名结构体（包括指针）的字段展开到当前结构体中，同encoding/json；
+// 没有omitempty且不是指针的字段为required，指针字段为nullable；
+func (swagger *Swagger) addStructFieldsToSchema(class *Struct, schema *spec.Schema) {
+	swagger.addFieldsToSchema(class.StructName, class.Fields, schema)
+}
+
+// addFieldsToSchema 将字段添加到schema的properties中，owner为字段所属结构体的名字，用于打印日志
+func (swagger *Swagger) addFieldsToSchema(owner string, fields []*Field, schema *spec.Schema) {
 	/*
 		"expireType": { //结构体格式
 			"$ref": "#/definitions/schema.ExpireType"
@@ -300,54 +554,134 @@ func (swagger *Swagger) addStructFieldsToSchema(class *Struct) map[string]spec.S
 			}
 		},
 	*/
-	for _, field := range class.Fields {
-		if field.Name == "" {
-			schemas1 := swagger.addStructFieldsToSchema(field.Type.(*Struct))
-			maps.Copy(schemas, schemas1)
+	for _, field := range fields {
+		name, option, hasOption := strings.Cut(field.Tags["json"], ",")
+		// json:"-,"表示字段名为-
+		if name == "-" && !hasOption {
 			continue
 		}
-		var name = field.Tags["json"]
-		if name == "-" {
+		if field.Name == "" && name == "" {
+			if embedded, ok := GetBasicType(field.Type).(*Struct); ok {
+				swagger.addStructFieldsToSchema(embedded, schema)
+				continue
+			}
+		}
+		// 函数和chan无法被json序列化，不出现在文档中
+		switch GetBasicType(field.Type).(type) {
+		case *FuncType, *ChanType:
 			continue
 		}
 		if len(name) == 0 {
-			name = FirstLower(field.Name)
+			name = FirstLower(fieldName(field))
 		}
 
-		schema := spec.Schema{
+		property := spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Description: field.Comment.comment,
 			},
 		}
-		if st, ok := field.Type.(SchemaType); ok {
-			st.InitSchema(&schema, swagger)
-		} else {
-			// struct.field可能是一个结构体，且从来没有被初始化为struct过；
-			// class1 := field.findStruct(true)
-			// if class1 != nil {
-			// 	class1.InitSchema(&schema, swagger)
-			// } else {
-			fmt.Printf("ERROR: field %s::%s %T is not a SchemaType\n", field.Type.IDName(), field.Name, field.Type)
-			// }
+		if field.Type == nil {
+			Warnf(field.Position(), "type of field %s::%s is not supported, use any instead", owner, name)
+		}
+		initTypeSchema(field.Type, &property, swagger)
+		if IsPointer(field.Type) {
+			property.AddExtension(nullableExtension, true)
+		} else if !strings.Contains(option, "omitempty") {
+			schema.AddRequired(name)
+		}
+		schema.SetProperty(name, property)
+	}
+}
+
+// fieldName 返回字段名，匿名字段为类型名
+func fieldName(field *Field) string {
+	if field.Name != "" || field.Type == nil {
+		return field.Name
+	}
+	name := GetBasicType(field.Type).RefName(nil)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// definitionName 返回结构体在definitions中的名字；
+// 默认为包名.结构体名，如biz.HelloRequest；配置为short时优先使用结构体名；
+// 名字已经被其他结构体占用时，使用模块全路径；
+func (swagger *Swagger) definitionName(class *Struct) string {
+	if class.goSource == nil {
+		return class.StructName
+	}
+	pkg := class.goSource.Pkg
+	candidates := []string{
+		pkg.GetName() + "." + class.StructName,
+		strings.ReplaceAll(pkg.Module, "/", ".") + "." + class.StructName,
+	}
+	if swagger.project.Cfg.SwaggerCfg.SchemaName == SchemaNameShort {
+		candidates = append([]string{class.StructName}, candidates...)
+	}
+	for _, name := range candidates {
+		if owner, ok := swagger.definitionOwner[name]; !ok || owner == class.IDName() {
+			swagger.definitionOwner[name] = class.IDName()
+			return name
 		}
-		schemas[name] = schema
 	}
-	return schemas
+	return candidates[len(candidates)-1]
 }
 
+// getRefOfStruct 生成结构体的definition，并返回其引用；
+// 结果记录在definitions中，每个结构体只生成一次；先记录ref再解析字段，递归引用自己的结构体会得到$ref而不会无限递归；
 func (swagger *Swagger) getRefOfStruct(class *Struct) *spec.Ref {
-	schemas := swagger.addStructFieldsToSchema(class)
-	result := spec.SchemaProps{
-		Type:       []string{"object"},
-		Properties: schemas,
+	if ref, ok := swagger.definitions[class]; ok {
+		return ref
 	}
-	ref, _ := spec.NewRef("#/definitions/" + class.StructName)
-	swagger.swag.Definitions[class.StructName] = spec.Schema{
-		SchemaProps: result,
+	name := swagger.definitionName(class)
+	ref := spec.MustCreateRef(definitionsPrefix + name)
+	swagger.definitions[class] = &ref
+	swagger.addDefinition(name, class.StructName, class.Fields)
+	return &ref
+}
+
+// getRefOfInstance 生成范型结构体实例化后的definition，字段使用替换后的类型；相同的实例化只生成一次；
+func (swagger *Swagger) getRefOfInstance(instance *InstanceType, class *Struct) *spec.Ref {
+	id := instance.IDName()
+	if ref, ok := swagger.instances[id]; ok {
+		return ref
 	}
+	name := swagger.instanceName(instance, class)
+	ref := spec.MustCreateRef(definitionsPrefix + name)
+	swagger.instances[id] = &ref
+	swagger.addDefinition(name, class.StructName, instance.Fields())
 	return &ref
 }
 
+// instanceName 返回实例化在definitions中的名字，为结构体的名字加上类型参数的名字，如other.Page-api.Req；
+// 不使用[]，避免$ref中出现需要转义的字符；
+func (swagger *Swagger) instanceName(instance *InstanceType, class *Struct) string {
+	var args []string
+	for _, arg := range instance.TypeArgs {
+		switch arg := GetBasicType(arg).(type) {
+		case nil:
+			args = append(args, "any")
+		case *Struct:
+			args = append(args, swagger.definitionName(arg))
+		case *InstanceType:
+			if origin, ok := arg.Typer.(*Struct); ok {
+				args = append(args, swagger.instanceName(arg, origin))
+			} else {
+				args = append(args, arg.RefName(nil))
+			}
+		default:
+			args = append(args, arg.RefName(nil))
+		}
+	}
+	return swagger.definitionName(class) + "-" + strings.Join(args, "-")
+}
+
+func (swagger *Swagger) addDefinition(name, owner string, fields []*Field) {
+	schema := spec.Schema{}
+	schema.Typed("object", "")
+	swagger.addFieldsToSchema(owner, fields, &schema)
+	swagger.swag.Definitions[name] = schema
+}
+
 func (swagger *Swagger) initResponseResult() {
 	class := Struct{
 		StructName: "ResponseResult",
@@ -381,8 +715,7 @@ func (swagger *Swagger) getSwaggerResponse(objField *Field) spec.Response {
 	var objSchema = spec.Schema{
 		SchemaProps: spec.SchemaProps{},
 	}
-	basicType := GetBasicType(objField.Type)
-	basicType.(SchemaType).InitSchema(&objSchema, swagger)
+	initTypeSchema(objField.Type, &objSchema, swagger)
 	ref := spec.Schema{
 		SchemaProps: spec.SchemaProps{
 			Type: []string{"object"},
@@ -0,0 +1,317 @@
+package astinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultSwaggerOutput = "swagger.json"
+	// 生成文档路由时，gen目录下保存的文档，通过go:embed嵌入到程序中；
+	embedSwaggerFile = "swagger_spec.json"
+	DocUIRedoc       = "redoc"
+	DocUISwagger     = "swagger-ui"
+	// DocAssets不为空时，页面使用的文件复制到gen下的该目录，通过go:embed嵌入到程序中
+	embedAssetsDir = "swagger_assets"
+)
+
+// docUIAsset 文档页面使用的js/css文件；Url为cdn上固定版本的地址，不使用latest等会变化的版本
+type docUIAsset struct {
+	Name        string
+	Url         string
+	ContentType string
+}
+
+// docUIAssets 各文档页面使用的文件；DocAssets中需要提供同名的文件
+var docUIAssets = map[string][]docUIAsset{
+	DocUISwagger: {
+		{
+			Name:        "swagger-ui.css",
+			Url:         "https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css",
+			ContentType: "text/css; charset=utf-8",
+		},
+		{
+			Name:        "swagger-ui-bundle.js",
+			Url:         "https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js",
+			ContentType: "application/javascript; charset=utf-8",
+		},
+	},
+	DocUIRedoc: {
+		{
+			Name:        "redoc.standalone.js",
+			Url:         "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js",
+			ContentType: "application/javascript; charset=utf-8",
+		},
+	},
+}
+
+// groupFileName 在文件名的后缀前加上group，如swagger.json=>swagger.admin.json；group为空时返回原文件名
+func groupFileName(fileName, group string) string {
+	if group == "" {
+		return fileName
+	}
+	ext := filepath.Ext(fileName)
+	return strings.TrimSuffix(fileName, ext) + "." + group + ext
+}
+
+// saveSwagger 将文档保存到cfg.Output中，并在需要时保存嵌入到gen中的文档；group不为空时，保存为该group的文档；
+// SplitByGroup时，文档路由使用各group的文档，所以不保存整体的嵌入文档；
+// json的key都是有序的（struct按定义顺序，map按key排序），所以每次生成的结果一致；
+func (swagger *Swagger) saveSwagger(swaggerJson []byte, cfg *SwaggerCfg, group string) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, swaggerJson, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	output := cfg.Output
+	if output == "" {
+		output = defaultSwaggerOutput
+	}
+	output = groupFileName(output, group)
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(swagger.project.currentProject.Path, output)
+	}
+	content := indented.Bytes()
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".yaml", ".yml":
+		var err error
+		if content, err = jsonToYaml(swaggerJson); err != nil {
+			return err
+		}
+	}
+	if err := GenOutput.WriteFile(output, content); err != nil {
+		return err
+	}
+	logSaved("swagger", output)
+	if cfg.DocPath != "" && cfg.SplitByGroup == (group != "") {
+		return GenOutput.WriteFile(filepath.Join(swagger.project.genDir(), groupFileName(embedSwaggerFile, group)), indented.Bytes())
+	}
+	return nil
+}
+
+// 通过yaml.Node转换，保持json中key的顺序
+func jsonToYaml(content []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, err
+	}
+	// json解析出来的是flow风格，改为block风格
+	var clearStyle func(node *yaml.Node)
+	clearStyle = func(node *yaml.Node) {
+		node.Style &^= yaml.FlowStyle
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+			node.Style &^= yaml.DoubleQuotedStyle
+		}
+		for _, child := range node.Content {
+			clearStyle(child)
+		}
+	}
+	clearStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+	return buf.Bytes(), nil
+}
+
+const docRouterTemplate = `
+const swaggerPage = ` + "`" + `{{.Page}}` + "`" + `
+{{range $i, $asset := .Assets}}
+//go:embed {{$asset.File}}
+var swaggerAsset{{$i}} []byte
+{{end}}
+{{range .Routers}}
+//go:embed {{.SpecFile}}
+var {{.SpecVar}} []byte
+
+// {{.Name}} 注册文档页面和文档内容的路由
+func {{.Name}}(engine *gin.Engine) {
+	engine.GET("{{$.DocPath}}", func(c *gin.Context) {
+		c.Data(200, "text/html; charset=utf-8", []byte(swaggerPage))
+	})
+	engine.GET("{{$.SpecPath}}", func(c *gin.Context) {
+		c.Data(200, "application/json; charset=utf-8", {{.SpecVar}})
+	})
+	{{- range $i, $asset := $.Assets}}
+	engine.GET("{{$asset.Path}}", func(c *gin.Context) {
+		c.Data(200, "{{$asset.ContentType}}", swaggerAsset{{$i}})
+	})
+	{{- end}}
+}
+{{end}}`
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API</title>
+<link rel="stylesheet" href="{{index .Urls "swagger-ui.css"}}">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{index .Urls "swagger-ui-bundle.js"}}"></script>
+<script>
+window.onload = function() {
+	SwaggerUIBundle({url: "{{.SpecPath}}", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>`
+
+const redocPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API</title>
+</head>
+<body>
+<redoc spec-url="{{.SpecPath}}"></redoc>
+<script src="{{index .Urls "redoc.standalone.js"}}"></script>
+</body>
+</html>`
+
+type docRouter struct {
+	Name     string
+	SpecFile string
+	SpecVar  string
+}
+
+// embedAsset 嵌入到程序中的页面文件，File为相对gen目录的路径，Path为路由
+type embedAsset struct {
+	File        string
+	Path        string
+	ContentType string
+}
+
+// genDocRouterCode 生成文档路由的代码，返回key为group，value为路由初始化函数名字的map；
+// SplitByGroup时每个group使用自己的文档，key为group；否则所有group使用同一个路由，key为空；DocPath为空时不生成；
+func genDocRouterCode(cfg *SwaggerCfg) map[string]string {
+	if cfg.DocPath == "" {
+		return nil
+	}
+	file := createGenedFile("swagger_doc")
+	file.GetImport(SimplePackage("embed", "_"))
+	file.GetImport(SimplePackage("github.com/gin-gonic/gin", "gin"))
+	docPath := "/" + strings.Trim(cfg.DocPath, "/")
+	data := struct {
+		DocPath  string
+		SpecPath string
+		Page     string
+		Routers  []docRouter
+		Assets   []embedAsset
+		Urls     map[string]string // 页面中使用的文件的地址，key为文件名
+	}{
+		DocPath:  docPath,
+		SpecPath: strings.TrimSuffix(docPath, "/") + "/swagger.json",
+		Urls:     make(map[string]string),
+	}
+	routers := make(map[string]string)
+	groups := []string{""}
+	if cfg.SplitByGroup {
+		groups = GlobalProject.servletGroups()
+	}
+	used := make(map[string]bool)
+	for _, group := range groups {
+		router := docRouter{
+			Name:     "initDocRouter",
+			SpecFile: groupFileName(embedSwaggerFile, group),
+			SpecVar:  "swaggerSpec",
+		}
+		if group != "" {
+			// group如admin-api不是合法的标识符，替换为admin_api；替换后重名时加上序号
+			suffix := identifierSuffix(group)
+			for i := 2; used[suffix]; i++ {
+				suffix = fmt.Sprintf("%s_%d", identifierSuffix(group), i)
+			}
+			used[suffix] = true
+			router.Name += "_" + suffix
+			router.SpecVar += "_" + suffix
+		}
+		data.Routers = append(data.Routers, router)
+		routers[group] = router.Name
+	}
+	page, ui := swaggerUIPage, DocUISwagger
+	switch cfg.DocUI {
+	case DocUIRedoc:
+		page, ui = redocPage, DocUIRedoc
+	case DocUISwagger, "":
+	default:
+		Warnf(token.Position{}, "unknown DocUI %s, use %s", cfg.DocUI, DocUISwagger)
+	}
+	assetsDir := filepath.Join(GlobalProject.genDir(), embedAssetsDir)
+	embedded := make(map[string]bool)
+	for _, asset := range docUIAssets[ui] {
+		if cfg.DocAssets == "" {
+			data.Urls[asset.Name] = asset.Url
+			continue
+		}
+		source := cfg.DocAssets
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(GlobalProject.currentProject.Path, source)
+		}
+		content, err := os.ReadFile(filepath.Join(source, asset.Name))
+		if err != nil {
+			Errorf(token.Position{}, "read DocAssets file %s failed: %s", asset.Name, err.Error())
+			continue
+		}
+		if err := GenOutput.WriteFile(filepath.Join(assetsDir, asset.Name), content); err != nil {
+			Errorf(token.Position{}, "save DocAssets file %s failed: %s", asset.Name, err.Error())
+			continue
+		}
+		path := strings.TrimSuffix(docPath, "/") + "/assets/" + asset.Name
+		data.Urls[asset.Name] = path
+		data.Assets = append(data.Assets, embedAsset{
+			File:        embedAssetsDir + "/" + asset.Name,
+			Path:        path,
+			ContentType: asset.ContentType,
+		})
+		embedded[asset.Name] = true
+	}
+	// 删除之前嵌入，现在不再使用的文件，如改为使用cdn或切换了DocUI
+	for _, assets := range docUIAssets {
+		for _, asset := range assets {
+			if !embedded[asset.Name] {
+				GenOutput.Remove(filepath.Join(assetsDir, asset.Name))
+			}
+		}
+	}
+	var pageContent strings.Builder
+	if err := template.Must(template.New("page").Parse(page)).Execute(&pageContent, data); err != nil {
+		log.Fatalf("Failed to execute doc page template: %v", err)
+	}
+	data.Page = pageContent.String()
+	tpl, err := template.New("docRouter").Parse(docRouterTemplate)
+	if err != nil {
+		log.Fatalf("Failed to parse doc router template: %v", err)
+	}
+	var content strings.Builder
+	if err := tpl.Execute(&content, data); err != nil {
+		log.Fatalf("Failed to execute doc router template: %v", err)
+	}
+	file.AddBuilder(&content)
+	file.save()
+	return routers
+}
+
+// identifierSuffix 将name中不能用于标识符的字符替换为_
+func identifierSuffix(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,258 @@
+package astinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpecPublisher 文档生成后，将文档发布到外部系统，如apifox，文档服务器，本地目录等；
+type SpecPublisher interface {
+	Name() string
+	// Publish 发布json格式的文档；返回PermanentError时不再重试
+	Publish(spec []byte) error
+	RetryPolicy() RetryCfg
+}
+
+var specPublishers []SpecPublisher
+
+// RegisterSpecPublisher 注册配置之外的发布目标
+func RegisterSpecPublisher(publisher ...SpecPublisher) {
+	specPublishers = append(specPublishers, publisher...)
+}
+
+// PermanentError 表示重试也不会成功的错误，如4xx
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	defaultRetries       = 2
+	defaultRetryInterval = time.Second
+	defaultApifoxUrl     = "https://api.apifox.com"
+)
+
+func (r RetryCfg) RetryPolicy() RetryCfg {
+	return r
+}
+
+// run 执行publish，失败后按照重试策略重试
+func (r RetryCfg) run(name string, publish func() error) error {
+	retries := r.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	interval := defaultRetryInterval
+	if r.RetryInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(r.RetryInterval); err != nil {
+			return fmt.Errorf("invalid RetryInterval %s of %s: %w", r.RetryInterval, name, err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		err := publish()
+		var permanent *PermanentError
+		if err == nil || attempt >= retries || errors.As(err, &permanent) {
+			return err
+		}
+		Warnf(token.Position{}, "publish to %s failed: %v, retry after %s", name, err, interval)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// publishers 返回配置中的发布目标和注册的发布目标
+func (swagger *Swagger) publishers(cfg *SwaggerCfg) []SpecPublisher {
+	var result []SpecPublisher
+	apifox := cfg.Publish.Apifox
+	if apifox == nil && cfg.Token != "" {
+		apifox = &ApifoxCfg{
+			ProjectId:     cfg.ProjectId,
+			ServletFolder: cfg.ServletFolder,
+			SchemaFolder:  cfg.SchemaFolder,
+			Token:         cfg.Token,
+		}
+	}
+	if apifox != nil {
+		result = append(result, &apifoxPublisher{ApifoxCfg: *apifox})
+	}
+	for _, httpCfg := range cfg.Publish.Http {
+		result = append(result, &httpPublisher{HttpPublishCfg: httpCfg})
+	}
+	for _, dirCfg := range cfg.Publish.Dir {
+		if dirCfg.FileName == "" {
+			dirCfg.FileName = filepath.Base(cfg.Output)
+			if cfg.Output == "" {
+				dirCfg.FileName = defaultSwaggerOutput
+			}
+		}
+		if !filepath.IsAbs(dirCfg.Path) {
+			dirCfg.Path = filepath.Join(swagger.project.currentProject.Path, dirCfg.Path)
+		}
+		result = append(result, &dirPublisher{DirPublishCfg: dirCfg})
+	}
+	return append(result, specPublishers...)
+}
+
+// publish 将文档发布到所有目标，返回所有失败的错误
+func (swagger *Swagger) publish(swaggerJson []byte, cfg *SwaggerCfg) error {
+	var errs []error
+	for _, publisher := range swagger.publishers(cfg) {
+		err := publisher.RetryPolicy().run(publisher.Name(), func() error {
+			return publisher.Publish(swaggerJson)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publish to %s failed: %w", publisher.Name(), err))
+			continue
+		}
+		Logf("published to %s\n", publisher.Name())
+	}
+	return errors.Join(errs...)
+}
+
+var publishClient = &http.Client{Timeout: 30 * time.Second}
+
+// sendRequest 发送请求，5xx和429可以重试，其他非2xx的返回为PermanentError
+func sendRequest(req *http.Request) ([]byte, error) {
+	response, err := publishClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode/100 == 2 {
+		return content, nil
+	}
+	err = fmt.Errorf("%s %s: %s %s", req.Method, req.URL, response.Status, strings.TrimSpace(string(content)))
+	if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+		return nil, err
+	}
+	return nil, &PermanentError{Err: err}
+}
+
+type apifoxPublisher struct {
+	ApifoxCfg
+}
+
+func (p *apifoxPublisher) Name() string {
+	return "apifox project " + strconv.Itoa(p.ProjectId)
+}
+
+func (p *apifoxPublisher) Publish(spec []byte) error {
+	cmdMap := map[string]any{
+		"input": string(spec),
+		"options": map[string]any{
+			"targetEndpointFolderId":        p.ServletFolder,
+			"targetSchemaFolderId":          p.SchemaFolder,
+			"endpointOverwriteBehavior":     "OVERWRITE_EXISTING",
+			"schemaOverwriteBehavior":       "OVERWRITE_EXISTING",
+			"updateFolderOfChangedEndpoint": false,
+			"prependBasePath":               false,
+		},
+	}
+	data, err := json.Marshal(cmdMap)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	base := p.Url
+	if base == "" {
+		base = defaultApifoxUrl
+	}
+	url := strings.TrimSuffix(base, "/") + "/v1/projects/" + strconv.Itoa(p.ProjectId) + "/import-openapi?locale=zh-CN"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Apifox-Api-Version", "2024-03-28")
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("User-Agent", "Apifox/1.0.0 (https://apifox.com)")
+	content, err := sendRequest(req)
+	if err != nil {
+		return err
+	}
+	// apifox在业务失败时也可能返回200，通过success判断
+	var result struct {
+		Success *bool `json:"success"`
+	}
+	if json.Unmarshal(content, &result) == nil && result.Success != nil && !*result.Success {
+		return &PermanentError{Err: fmt.Errorf("apifox import failed: %s", strings.TrimSpace(string(content)))}
+	}
+	return nil
+}
+
+type httpPublisher struct {
+	HttpPublishCfg
+}
+
+func (p *httpPublisher) Name() string {
+	return p.Url
+}
+
+func (p *httpPublisher) Publish(spec []byte) error {
+	method := strings.ToUpper(p.Method)
+	if method == "" {
+		method = http.MethodPut
+	}
+	req, err := http.NewRequest(method, p.Url, bytes.NewReader(spec))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+	_, err = sendRequest(req)
+	return err
+}
+
+type dirPublisher struct {
+	DirPublishCfg
+}
+
+func (p *dirPublisher) Name() string {
+	return filepath.Join(p.Path, p.FileName)
+}
+
+func (p *dirPublisher) Publish(spec []byte) error {
+	var content bytes.Buffer
+	if err := json.Indent(&content, spec, "", "  "); err != nil {
+		return &PermanentError{Err: err}
+	}
+	content.WriteByte('\n')
+	data := content.Bytes()
+	switch strings.ToLower(filepath.Ext(p.FileName)) {
+	case ".yaml", ".yml":
+		var err error
+		if data, err = jsonToYaml(spec); err != nil {
+			return &PermanentError{Err: err}
+		}
+	}
+	if err := os.MkdirAll(p.Path, 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(p.Name(), data, 0660)
+}
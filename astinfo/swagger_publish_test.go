@@ -0,0 +1,200 @@
+package astinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const publishSpec = `{"swagger":"2.0","info":{"title":"test","version":"1.0"}}`
+
+// publishServer 返回依次使用statuses作为状态码的服务器，超出时使用最后一个；count记录收到的请求数
+func publishServer(t *testing.T, statuses []int, check func(r *http.Request, body []byte)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var count atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(count.Add(1))
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if check != nil {
+			check(r, body)
+		}
+		status := statuses[min(n, len(statuses))-1]
+		w.WriteHeader(status)
+		if status/100 == 2 {
+			io.WriteString(w, `{"success":true}`)
+		} else {
+			io.WriteString(w, "failed")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &count
+}
+
+// resetDiagnostics 重试时通过Warnf报告，测试之间不共享；不输出日志
+func resetDiagnostics(t *testing.T) {
+	old := Diagnostics
+	Diagnostics = &DiagnosticCollector{Level: SeverityError, Output: io.Discard}
+	t.Cleanup(func() {
+		Diagnostics = old
+	})
+}
+
+func TestHttpPublisher(t *testing.T) {
+	resetDiagnostics(t)
+	server, count := publishServer(t, []int{http.StatusOK}, func(r *http.Request, body []byte) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.Header.Get("X-Env"); got != "test" {
+			t.Errorf("X-Env = %q", got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if string(body) != publishSpec {
+			t.Errorf("body = %s", body)
+		}
+	})
+	publisher := &httpPublisher{HttpPublishCfg{
+		Url:     server.URL + "/spec",
+		Token:   "secret",
+		Headers: map[string]string{"X-Env": "test"},
+	}}
+	if err := publisher.Publish([]byte(publishSpec)); err != nil {
+		t.Fatal(err)
+	}
+	if count.Load() != 1 {
+		t.Errorf("requests = %d, want 1", count.Load())
+	}
+}
+
+func TestApifoxPublisher(t *testing.T) {
+	resetDiagnostics(t)
+	server, count := publishServer(t, []int{http.StatusOK}, func(r *http.Request, body []byte) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/projects/12/import-openapi" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var cmd struct {
+			Input   string         `json:"input"`
+			Options map[string]any `json:"options"`
+		}
+		if err := json.Unmarshal(body, &cmd); err != nil {
+			t.Error(err)
+			return
+		}
+		if cmd.Input != publishSpec {
+			t.Errorf("input = %s", cmd.Input)
+		}
+		if cmd.Options["targetEndpointFolderId"] != float64(3) || cmd.Options["targetSchemaFolderId"] != float64(4) {
+			t.Errorf("options = %v", cmd.Options)
+		}
+	})
+	publisher := &apifoxPublisher{ApifoxCfg{
+		Url:           server.URL + "/",
+		ProjectId:     12,
+		ServletFolder: 3,
+		SchemaFolder:  4,
+		Token:         "token",
+	}}
+	if err := publisher.Publish([]byte(publishSpec)); err != nil {
+		t.Fatal(err)
+	}
+	if count.Load() != 1 {
+		t.Errorf("requests = %d, want 1", count.Load())
+	}
+}
+
+func TestApifoxPublisherBusinessFailure(t *testing.T) {
+	resetDiagnostics(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"success":false,"errorMessage":"bad spec"}`)
+	}))
+	defer server.Close()
+	publisher := &apifoxPublisher{ApifoxCfg{Url: server.URL, ProjectId: 1}}
+	var permanent *PermanentError
+	if err := publisher.Publish([]byte(publishSpec)); !errors.As(err, &permanent) {
+		t.Fatalf("err = %v, want PermanentError", err)
+	}
+}
+
+func TestPublishRetryOn5xx(t *testing.T) {
+	resetDiagnostics(t)
+	server, count := publishServer(t, []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, nil)
+	publisher := &httpPublisher{HttpPublishCfg{
+		Url:      server.URL,
+		RetryCfg: RetryCfg{Retries: 2, RetryInterval: "1ms"},
+	}}
+	err := publisher.RetryPolicy().run(publisher.Name(), func() error {
+		return publisher.Publish([]byte(publishSpec))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Load() != 3 {
+		t.Errorf("requests = %d, want 3", count.Load())
+	}
+	if warnings := Diagnostics.Count(SeverityWarning); warnings != 2 {
+		t.Errorf("warnings = %d, want 2", warnings)
+	}
+}
+
+func TestPublishNoRetryOn4xx(t *testing.T) {
+	resetDiagnostics(t)
+	server, count := publishServer(t, []int{http.StatusUnauthorized}, nil)
+	publisher := &httpPublisher{HttpPublishCfg{
+		Url:      server.URL,
+		RetryCfg: RetryCfg{Retries: 3, RetryInterval: "1ms"},
+	}}
+	err := publisher.RetryPolicy().run(publisher.Name(), func() error {
+		return publisher.Publish([]byte(publishSpec))
+	})
+	var permanent *PermanentError
+	if !errors.As(err, &permanent) {
+		t.Fatalf("err = %v, want PermanentError", err)
+	}
+	if count.Load() != 1 {
+		t.Errorf("requests = %d, want 1", count.Load())
+	}
+}
+
+// TestPublishError 一个目标失败时，其他目标仍然发布，返回的错误中包含失败的目标，gos以非0退出
+func TestPublishError(t *testing.T) {
+	resetDiagnostics(t)
+	failed, failedCount := publishServer(t, []int{http.StatusInternalServerError}, nil)
+	ok, okCount := publishServer(t, []int{http.StatusOK}, nil)
+	cfg := &SwaggerCfg{
+		Publish: PublishCfg{
+			Http: []HttpPublishCfg{
+				{Url: failed.URL, RetryCfg: RetryCfg{Retries: 1, RetryInterval: "1ms"}},
+				{Url: ok.URL},
+			},
+		},
+	}
+	err := (&Swagger{}).publish([]byte(publishSpec), cfg)
+	if err == nil {
+		t.Fatal("publish should fail")
+	}
+	if !strings.Contains(err.Error(), "publish to "+failed.URL+" failed") {
+		t.Errorf("err = %v", err)
+	}
+	if failedCount.Load() != 2 {
+		t.Errorf("requests to failed server = %d, want 2", failedCount.Load())
+	}
+	if okCount.Load() != 1 {
+		t.Errorf("requests to ok server = %d, want 1", okCount.Load())
+	}
+}
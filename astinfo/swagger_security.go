@@ -0,0 +1,91 @@
+package astinfo
+
+import (
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// filter和servlet通过 @gos security=xxx 声明认证方式，多个以逗号分割：
+// 1. bearer Authorization: Bearer xxx；
+// 2. basic http basic认证；
+// 3. query:name 通过query参数name传递的key；
+// 4. header:Name或Name 通过header Name传递的key；
+const (
+	SecurityBearer = "bearer"
+	SecurityBasic  = "basic"
+)
+
+// securityScheme 返回security声明对应的securityDefinitions中的名字和定义
+func securityScheme(security string) (string, *spec.SecurityScheme) {
+	switch security {
+	case SecurityBearer:
+		scheme := spec.APIKeyAuth("Authorization", "header")
+		scheme.AddExtension("x-scheme", SecurityBearer)
+		return SecurityBearer, scheme
+	case SecurityBasic:
+		return SecurityBasic, spec.BasicAuth()
+	}
+	in, name, ok := strings.Cut(security, ":")
+	if !ok {
+		in, name = "header", security
+	}
+	return name, spec.APIKeyAuth(name, in)
+}
+
+// servletFilters 返回作用于servlet的filter，与生成路由时的规则一致：
+// 1. 同group中没有url的filter作用于整个server；
+// 2. 同group中有url的filter，作用于url包含该url的servlet；
+// 3. servlet的filters注释中列出的filter；
+func (mp *MainProject) servletFilters(servlet *Method) []*Function {
+	groupName := servlet.Receiver.Comment.GroupName
+	methodUrl := strings.Trim(servlet.Comment.Url, "\"")
+	userFilters := make(map[string]bool)
+	for _, name := range strings.Split(servlet.Comment.Filter, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			userFilters[name] = true
+		}
+	}
+	var filters []*Function
+	for _, pkg := range sortedValues(mp.Packages) {
+		for _, filter := range pkg.Filter {
+			url := strings.Trim(filter.Comment.Url, "\"")
+			switch {
+			case userFilters[filter.Name]:
+			case filter.Comment.groupName != groupName:
+				continue
+			case url != "" && !strings.Contains(methodUrl, url):
+				continue
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+// addSecurity 将servlet和其filter声明的认证方式添加到operation和securityDefinitions中；
+// 所有的认证方式都需要满足，所以放在同一个security requirement中；
+func (swagger *Swagger) addSecurity(servlet *Method, operation *spec.Operation) {
+	var securities []string
+	for _, filter := range swagger.project.servletFilters(servlet) {
+		securities = append(securities, filter.Comment.security...)
+	}
+	securities = append(securities, servlet.Comment.security...)
+	requirement := make(map[string][]string)
+	for _, security := range securities {
+		security = strings.TrimSpace(security)
+		if security == "" {
+			continue
+		}
+		name, scheme := securityScheme(security)
+		if old, ok := swagger.swag.SecurityDefinitions[name]; ok && (old.Type != scheme.Type || old.In != scheme.In) {
+			Warnf(servlet.Position(), "security %s of %s conflicts with another definition of %s", security, servlet.Name, name)
+			continue
+		}
+		swagger.swag.SecurityDefinitions[name] = scheme
+		requirement[name] = []string{}
+	}
+	if len(requirement) > 0 {
+		operation.Security = []map[string][]string{requirement}
+	}
+}
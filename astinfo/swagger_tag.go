@@ -0,0 +1,87 @@
+package astinfo
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/go-openapi/spec"
+)
+
+// 每个servlet的tag默认为receiver的结构体名，可以通过struct或servlet上的 @gos tag=xxx 修改；
+// tag的描述来自结构体的注释；tag按照server的group分组，记录在x-tagGroups中，redoc等工具据此分组显示；
+const tagGroupsExtension = "x-tagGroups"
+
+// tagGroup x-tagGroups中的一项
+type tagGroup struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// operationTags 返回servlet的tag，并记录tag的描述和所属的group；
+// 除receiver的tag外，还加上server的group作为tag，便于在swagger-ui中按group查看；group的tag不加入x-tagGroups，redoc中不重复显示
+func (swagger *Swagger) operationTags(servlet *Method) []string {
+	receiver := servlet.Receiver
+	receiverTag := receiver.Comment.Tag
+	if receiverTag == "" {
+		receiverTag = receiver.StructName
+	}
+	name := servlet.Comment.tag
+	if name == "" {
+		name = receiverTag
+	}
+	if _, ok := swagger.tags[name]; !ok {
+		tag := spec.NewTag(name, "", nil)
+		swagger.tags[name] = &tag
+	}
+	if name == receiverTag && swagger.tags[name].Description == "" {
+		swagger.tags[name].Description = receiver.Comment.Doc
+	}
+	group := receiver.Comment.GroupName
+	if !slices.Contains(swagger.tagGroups[group], name) {
+		swagger.tagGroups[group] = append(swagger.tagGroups[group], name)
+	}
+	if group == "" || group == name {
+		return []string{name}
+	}
+	if _, ok := swagger.tags[group]; !ok {
+		tag := spec.NewTag(group, "group "+group+"中的所有接口", nil)
+		swagger.tags[group] = &tag
+	}
+	return []string{name, group}
+}
+
+// addTags 将tag和tag分组按名字排序后添加到文档中
+func (swagger *Swagger) addTags() {
+	swagger.swag.Tags = nil
+	for _, name := range sortedKeys(swagger.tags) {
+		swagger.swag.Tags = append(swagger.swag.Tags, *swagger.tags[name])
+	}
+	if len(swagger.tagGroups) == 0 {
+		return
+	}
+	var groups []tagGroup
+	for _, name := range sortedKeys(swagger.tagGroups) {
+		tags := slices.Clone(swagger.tagGroups[name])
+		sort.Strings(tags)
+		groups = append(groups, tagGroup{Name: name, Tags: tags})
+	}
+	// AddExtension会将key转为小写，x-tagGroups需要保持大小写
+	if swagger.swag.Extensions == nil {
+		swagger.swag.Extensions = spec.Extensions{}
+	}
+	swagger.swag.Extensions[tagGroupsExtension] = groups
+}
+
+// servletGroups 返回包含servlet的group，按名字排序
+func (mp *MainProject) servletGroups() []string {
+	var groups []string
+	for _, pkg := range sortedValues(mp.Packages) {
+		for _, class := range sortedValues(pkg.Structs) {
+			if class.Comment.serverType == Servlet && !slices.Contains(groups, class.Comment.GroupName) {
+				groups = append(groups, class.Comment.GroupName)
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
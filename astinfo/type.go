@@ -102,6 +102,14 @@ type MapType struct {
 	ValueTyper Typer
 }
 
+// RefName 键值类型都解析成功时返回map[K]V，否则返回map
+func (m *MapType) RefName(genFile *GenedFile) string {
+	if m.KeyTyper == nil || m.ValueTyper == nil {
+		return m.typeName
+	}
+	return "map[" + m.KeyTyper.RefName(genFile) + "]" + m.ValueTyper.RefName(genFile)
+}
+
 type RawType struct {
 	BaseType
 }
@@ -3,10 +3,11 @@ package astinfo
 import "go/ast"
 
 type Alias struct {
-	Equal    bool
-	Name     string
-	astRoot  *ast.TypeSpec
-	Gosourse *Gosourse
+	Equal         bool
+	Name          string
+	TypeParameter []*Field
+	astRoot       *ast.TypeSpec
+	Gosourse      *Gosourse
 	Typer
 }
 
@@ -46,11 +47,7 @@ func (a *Alias) GenConstructCode(genFile *GenedFile, wire bool) string {
 
 // Parse() error
 func (a *Alias) Parse() error {
-	var typeMap map[string]*Field
-	if a.astRoot.TypeParams != nil {
-		typeParameter := parseFields(a.astRoot.TypeParams.List, a.Gosourse, nil)
-		typeMap = FieldListToMap(typeParameter)
-	}
-	a.Typer = parseType(a.astRoot.Type, a.Gosourse, typeMap)
+	a.TypeParameter = parseTypeParams(a.astRoot.TypeParams, a.Gosourse)
+	a.Typer = parseType(a.astRoot.Type, a.Gosourse, FieldListToMap(a.TypeParameter))
 	return nil
 }
@@ -0,0 +1,256 @@
+package astinfo
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// AnonymousStruct 匿名结构体，如 Data struct{ Id int }；
+// 与Struct一样参与构造，nil数组初始化和swagger生成，只是没有名字，引用时展开为结构体定义；
+type AnonymousStruct struct {
+	goSource *Gosourse
+	Fields   []*Field
+}
+
+func NewAnonymousStruct(structType *ast.StructType, goSource *Gosourse, typeMap map[string]*Field) *AnonymousStruct {
+	return &AnonymousStruct{
+		goSource: goSource,
+		Fields:   parseFields(structType.Fields.List, goSource, typeMap),
+	}
+}
+
+// RefName 返回结构体的定义，如struct{ Id int `json:"id"` }
+func (s *AnonymousStruct) RefName(genFile *GenedFile) string {
+	return s.define(func(typer Typer) string {
+		return typer.RefName(genFile)
+	})
+}
+
+func (s *AnonymousStruct) IDName() string {
+	return s.define(Typer.IDName)
+}
+
+func (s *AnonymousStruct) define(typeName func(Typer) string) string {
+	var fields []string
+	for _, field := range s.Fields {
+		var sb strings.Builder
+		if field.Name != "" {
+			sb.WriteString(field.Name + " ")
+		}
+		sb.WriteString(anyTypeName(field.Type, typeName))
+		if field.astTag != nil {
+			sb.WriteString(" " + field.astTag.Value)
+		}
+		fields = append(fields, sb.String())
+	}
+	return "struct{ " + strings.Join(fields, "; ") + " }"
+}
+
+func (s *AnonymousStruct) GenConstructCode(genFile *GenedFile, wire bool) string {
+	var sb strings.Builder
+	sb.WriteString(s.RefName(genFile) + "{\n")
+	genFieldsConstructCode(&sb, s.Fields, genFile, wire)
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (s *AnonymousStruct) GenNilCode(file *GenedFile) string {
+	return genTypeNilCode(s, file, make(map[string]bool))
+}
+
+func (s *AnonymousStruct) Parse() error {
+	return nil
+}
+
+// AnonymousInterface 匿名interface，如 Handler interface{ Handle() error }；空interface解析为any
+type AnonymousInterface struct {
+	goSource *Gosourse
+	Embeds   []Typer // 嵌入的interface
+	Methods  []*InterfaceField
+}
+
+func NewAnonymousInterface(interfaceType *ast.InterfaceType, goSource *Gosourse, typeMap map[string]*Field) Typer {
+	if len(interfaceType.Methods.List) == 0 {
+		return GetRawType("any")
+	}
+	iface := &AnonymousInterface{
+		goSource: goSource,
+	}
+	for _, method := range interfaceType.Methods.List {
+		if len(method.Names) == 0 {
+			iface.Embeds = append(iface.Embeds, parseType(method.Type, goSource, typeMap))
+			continue
+		}
+		methodField := NewInterfaceField(method, goSource)
+		methodField.parse(typeMap)
+		iface.Methods = append(iface.Methods, methodField)
+	}
+	return iface
+}
+
+// RefName 返回interface的定义，如interface{ Handle(context.Context) error }
+func (i *AnonymousInterface) RefName(genFile *GenedFile) string {
+	return i.define(func(typer Typer) string {
+		return typer.RefName(genFile)
+	})
+}
+
+func (i *AnonymousInterface) IDName() string {
+	return i.define(Typer.IDName)
+}
+
+func (i *AnonymousInterface) define(typeName func(Typer) string) string {
+	var methods []string
+	for _, embed := range i.Embeds {
+		methods = append(methods, anyTypeName(embed, typeName))
+	}
+	fieldTypes := func(fields []*Field) string {
+		var names []string
+		for _, field := range fields {
+			names = append(names, anyTypeName(field.Type, typeName))
+		}
+		return strings.Join(names, ", ")
+	}
+	for _, method := range i.Methods {
+		signature := method.Name + "(" + fieldTypes(method.Params) + ")"
+		switch len(method.Results) {
+		case 0:
+		case 1:
+			signature += " " + fieldTypes(method.Results)
+		default:
+			signature += " (" + fieldTypes(method.Results) + ")"
+		}
+		methods = append(methods, signature)
+	}
+	return "interface{ " + strings.Join(methods, "; ") + " }"
+}
+
+func (i *AnonymousInterface) GenConstructCode(genFile *GenedFile, wire bool) string {
+	return "nil"
+}
+
+func (i *AnonymousInterface) Parse() error {
+	return nil
+}
+
+// anyTypeName 无法解析的类型使用any
+func anyTypeName(typer Typer, typeName func(Typer) string) string {
+	if typer == nil {
+		return "any"
+	}
+	return typeName(typer)
+}
+
+// FuncType 函数类型，如 OnExit func(int) error；json无法序列化函数，swagger中跳过该类型的字段
+type FuncType struct {
+	Params   []Typer
+	Results  []Typer
+	Variadic bool // 最后一个参数为...T，Params中记录为[]T
+}
+
+func NewFuncType(funcType *ast.FuncType, goSource *Gosourse, typeMap map[string]*Field) *FuncType {
+	result := &FuncType{}
+	fieldTypes := func(fieldList *ast.FieldList) []Typer {
+		if fieldList == nil {
+			return nil
+		}
+		var typers []Typer
+		for _, field := range fieldList.List {
+			fieldType := field.Type
+			if ellipsis, ok := fieldType.(*ast.Ellipsis); ok {
+				result.Variadic = true
+				fieldType = &ast.ArrayType{Lbrack: ellipsis.Pos(), Elt: ellipsis.Elt}
+			}
+			typer := parseType(fieldType, goSource, typeMap)
+			for range max(len(field.Names), 1) {
+				typers = append(typers, typer)
+			}
+		}
+		return typers
+	}
+	result.Params = fieldTypes(funcType.Params)
+	result.Results = fieldTypes(funcType.Results)
+	return result
+}
+
+// RefName 返回函数的定义，如func(context.Context, ...string) error
+func (f *FuncType) RefName(genFile *GenedFile) string {
+	return f.define(func(typer Typer) string {
+		return typer.RefName(genFile)
+	})
+}
+
+func (f *FuncType) IDName() string {
+	return f.define(Typer.IDName)
+}
+
+func (f *FuncType) define(typeName func(Typer) string) string {
+	var params, results []string
+	for i, param := range f.Params {
+		if array, ok := param.(*ArrayType); ok && f.Variadic && i == len(f.Params)-1 {
+			params = append(params, "..."+anyTypeName(array.Typer, typeName))
+			continue
+		}
+		params = append(params, anyTypeName(param, typeName))
+	}
+	for _, result := range f.Results {
+		results = append(results, anyTypeName(result, typeName))
+	}
+	signature := "func(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		signature += " " + results[0]
+	default:
+		signature += " (" + strings.Join(results, ", ") + ")"
+	}
+	return signature
+}
+
+func (f *FuncType) GenConstructCode(genFile *GenedFile, wire bool) string {
+	return "nil"
+}
+
+func (f *FuncType) Parse() error {
+	return nil
+}
+
+// ChanType chan类型，如 Done <-chan struct{}；与函数一样无法序列化，swagger中跳过该类型的字段
+type ChanType struct {
+	Dir  ast.ChanDir
+	Elem Typer
+}
+
+// RefName 返回chan的定义，如chan<- int
+func (c *ChanType) RefName(genFile *GenedFile) string {
+	return c.define(func(typer Typer) string {
+		return typer.RefName(genFile)
+	})
+}
+
+func (c *ChanType) IDName() string {
+	return c.define(Typer.IDName)
+}
+
+func (c *ChanType) define(typeName func(Typer) string) string {
+	elem := anyTypeName(c.Elem, typeName)
+	switch c.Dir {
+	case ast.SEND:
+		return "chan<- " + elem
+	case ast.RECV:
+		return "<-chan " + elem
+	}
+	// chan (<-chan int)需要括号，否则会被解析为chan<- chan int
+	if inner, ok := c.Elem.(*ChanType); ok && inner.Dir == ast.RECV {
+		elem = "(" + elem + ")"
+	}
+	return "chan " + elem
+}
+
+func (c *ChanType) GenConstructCode(genFile *GenedFile, wire bool) string {
+	return "nil"
+}
+
+func (c *ChanType) Parse() error {
+	return nil
+}
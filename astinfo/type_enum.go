@@ -0,0 +1,197 @@
+package astinfo
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// EnumValue 带类型的常量，如 const StatusOk Status = 1，供swagger生成enum使用；
+type EnumValue struct {
+	Name  string
+	Value any
+}
+
+// constSpec 一个常量的定义；省略类型和值的隐式重复已经展开
+type constSpec struct {
+	name     string
+	typeName string // 常量的类型，不是本包的类型时为空
+	expr     ast.Expr
+	iota     int
+}
+
+// parseConstDecl 记录const定义，所有文件解析完成后由evalConsts统一计算；
+// 常量可以引用后面的文件中定义的常量，所以不能在解析文件时计算；
+func (g *Gosourse) parseConstDecl(genDecl *ast.GenDecl) {
+	var typeName string
+	var values []ast.Expr
+	for iota, spec := range genDecl.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		if valueSpec.Type != nil || len(valueSpec.Values) != 0 {
+			typeName = ""
+			if ident, ok := valueSpec.Type.(*ast.Ident); ok {
+				typeName = ident.Name
+			}
+			values = valueSpec.Values
+		}
+		for i, name := range valueSpec.Names {
+			if i >= len(values) {
+				break
+			}
+			g.Pkg.constSpecs = append(g.Pkg.constSpecs, &constSpec{
+				name:     name.Name,
+				typeName: typeName,
+				expr:     values[i],
+				iota:     iota,
+			})
+		}
+	}
+}
+
+// evalConsts 计算包中所有的常量，记录带类型的常量；
+// 支持iota，省略类型和值的隐式重复，以及常量间的简单运算(与定义顺序无关)；无法计算的常量忽略；
+func (pkg *Package) evalConsts() {
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+				for _, spec := range genDecl.Specs {
+					pkg.constTypes[spec.(*ast.TypeSpec).Name.Name] = true
+				}
+			}
+		}
+	}
+	// 每一轮计算引用的常量都已知的常量，直到没有新的常量可以计算
+	pending := pkg.constSpecs
+	for len(pending) > 0 {
+		var next []*constSpec
+		for _, spec := range pending {
+			value := evalConst(spec.expr, spec.iota, pkg.Consts, pkg.constTypes)
+			if value.Kind() == constant.Unknown {
+				next = append(next, spec)
+				continue
+			}
+			if spec.name != "_" {
+				pkg.Consts[spec.name] = value
+			}
+		}
+		if len(next) == len(pending) {
+			break
+		}
+		pending = next
+	}
+	// enum按照定义顺序排列
+	for _, spec := range pkg.constSpecs {
+		value, ok := pkg.Consts[spec.name]
+		if !ok || spec.typeName == "" || spec.name == "_" || GetRawType(spec.typeName) != nil {
+			continue
+		}
+		pkg.Enums[spec.typeName] = append(pkg.Enums[spec.typeName], &EnumValue{
+			Name:  spec.name,
+			Value: constantValue(value),
+		})
+	}
+}
+
+// evalConst 计算常量表达式；typeNames为包中定义的类型，用于识别类型转换；
+// 类型不匹配等go/constant会panic的情况，返回Unknown；
+func evalConst(expr ast.Expr, iota int, known map[string]constant.Value, typeNames map[string]bool) (value constant.Value) {
+	unknown := constant.MakeUnknown()
+	defer func() {
+		if recover() != nil {
+			value = unknown
+		}
+	}()
+	eval := func(expr ast.Expr) constant.Value {
+		return evalConst(expr, iota, known, typeNames)
+	}
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(expr.Value, expr.Kind, 0)
+	case *ast.Ident:
+		switch expr.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota))
+		case "true", "false":
+			return constant.MakeBool(expr.Name == "true")
+		}
+		if value, ok := known[expr.Name]; ok {
+			return value
+		}
+	case *ast.ParenExpr:
+		return eval(expr.X)
+	case *ast.CallExpr:
+		// 类型转换，如Status(1)；len，unsafe.Sizeof等内置函数无法计算
+		if len(expr.Args) == 1 && isConversion(expr.Fun, typeNames) {
+			return eval(expr.Args[0])
+		}
+	case *ast.UnaryExpr:
+		x := eval(expr.X)
+		if x.Kind() == constant.Unknown {
+			return unknown
+		}
+		return constant.UnaryOp(expr.Op, x, 0)
+	case *ast.BinaryExpr:
+		x := eval(expr.X)
+		y := eval(expr.Y)
+		if x.Kind() == constant.Unknown || y.Kind() == constant.Unknown {
+			return unknown
+		}
+		switch expr.Op {
+		case token.SHL, token.SHR:
+			shift, ok := constant.Uint64Val(y)
+			if !ok {
+				return unknown
+			}
+			return constant.Shift(x, expr.Op, uint(shift))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(x, expr.Op, y))
+		case token.QUO:
+			if y.Kind() == constant.Int && constant.Sign(y) == 0 {
+				return unknown
+			}
+			// 整数相除结果仍为整数
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y)
+			}
+		}
+		return constant.BinaryOp(x, expr.Op, y)
+	}
+	return unknown
+}
+
+// isConversion 判断fun是否为类型：基本类型，包中定义的类型，或者其他包中的类型(如time.Duration)；
+// 常量表达式中只能调用内置函数和unsafe中的函数，所以其他包的导出名字都是类型
+func isConversion(fun ast.Expr, typeNames map[string]bool) bool {
+	switch fun := fun.(type) {
+	case *ast.ParenExpr:
+		return isConversion(fun.X, typeNames)
+	case *ast.Ident:
+		return GetRawType(fun.Name) != nil || typeNames[fun.Name]
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		return ok && pkg.Name != "unsafe" && fun.Sel.IsExported()
+	}
+	return false
+}
+
+// constantValue 将常量转换为json可以表达的值
+func constantValue(value constant.Value) any {
+	switch value.Kind() {
+	case constant.Bool:
+		return constant.BoolVal(value)
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Int:
+		if v, ok := constant.Int64Val(value); ok {
+			return v
+		}
+		if v, ok := constant.Uint64Val(value); ok {
+			return v
+		}
+	case constant.Float:
+		v, _ := constant.Float64Val(value)
+		return v
+	}
+	// 超出范围的值以字符串形式保存
+	return value.ExactString()
+}
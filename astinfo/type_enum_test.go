@@ -0,0 +1,81 @@
+package astinfo
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+// parseConstFiles 按照文件顺序记录files中的常量定义并计算
+func parseConstFiles(t *testing.T, files ...string) *Package {
+	t.Helper()
+	pkg := NewPackage("example.com/enum", false, "/enum")
+	pkg.fset = token.NewFileSet()
+	pkg.Files = make(map[string]*ast.File)
+	for i, content := range files {
+		name := string(rune('a'+i)) + ".go"
+		f, err := parser.ParseFile(pkg.fset, name, content, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg.Files[name] = f
+		g := NewGosourse(f, pkg, name)
+		for _, decl := range f.Decls {
+			if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.CONST {
+				g.parseConstDecl(genDecl)
+			}
+		}
+	}
+	pkg.evalConsts()
+	return pkg
+}
+
+func TestEvalConsts(t *testing.T) {
+	pkg := parseConstFiles(t, `package enum
+
+import "unsafe"
+
+type Status int
+
+const (
+	StatusOk Status = Base + iota
+	StatusFailed
+	StatusLen Status = Status(len("abc"))
+	StatusSize Status = Status(unsafe.Sizeof(0))
+	StatusConv = Status(Base * 2)
+)
+
+const Name = len("abc")
+`, `package enum
+
+import "time"
+
+const Base = 10
+
+const Timeout = time.Duration(Base)
+`)
+	var got []EnumValue
+	for _, value := range pkg.Enums["Status"] {
+		got = append(got, *value)
+	}
+	want := []EnumValue{{"StatusOk", int64(10)}, {"StatusFailed", int64(11)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("enums = %v, want %v", got, want)
+	}
+	for name, want := range map[string]constant.Value{
+		"StatusConv": constant.MakeInt64(20),
+		"Timeout":    constant.MakeInt64(10),
+	} {
+		if value, ok := pkg.Consts[name]; !ok || constant.Compare(value, token.NEQ, want) {
+			t.Errorf("%s = %v, want %v", name, value, want)
+		}
+	}
+	for _, name := range []string{"StatusLen", "StatusSize", "Name"} {
+		if value, ok := pkg.Consts[name]; ok {
+			t.Errorf("%s should not be evaluated, got %v", name, value)
+		}
+	}
+}
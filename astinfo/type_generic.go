@@ -0,0 +1,171 @@
+package astinfo
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// TypeParam 范型参数，如type Page[T any]中的T；实例化时被替换为对应的类型参数
+type TypeParam struct {
+	Name       string
+	Constraint Typer // 约束，如any，comparable
+	Index      int   // 在类型参数列表中的位置
+}
+
+func (p *TypeParam) RefName(_ *GenedFile) string {
+	return p.Name
+}
+
+func (p *TypeParam) IDName() string {
+	return p.Name
+}
+
+func (p *TypeParam) GenConstructCode(_ *GenedFile, _ bool) string {
+	return "*new(" + p.Name + ")"
+}
+
+func (p *TypeParam) Parse() error {
+	return nil
+}
+
+// parseTypeParams 解析结构体和类型定义的范型参数，参数的类型为TypeParam；
+// 约束中可以引用范型参数自己，如Curve[P Point[P]]，所以先创建所有参数再解析约束；
+func parseTypeParams(typeParams *ast.FieldList, goSource *Gosourse) []*Field {
+	if typeParams == nil {
+		return nil
+	}
+	var fields []*Field
+	var params [][]*TypeParam
+	for _, param := range typeParams.List {
+		var group []*TypeParam
+		for _, name := range param.Names {
+			typeParam := &TypeParam{
+				Name:  name.Name,
+				Index: len(fields),
+			}
+			group = append(group, typeParam)
+			fields = append(fields, NewSimpleField(typeParam, name.Name))
+		}
+		params = append(params, group)
+	}
+	typeMap := FieldListToMap(fields)
+	for i, param := range typeParams.List {
+		constraint := parseType(param.Type, goSource, typeMap)
+		for _, typeParam := range params[i] {
+			typeParam.Constraint = constraint
+		}
+	}
+	return fields
+}
+
+// InstanceType 范型类型的实例化，如PageResult[User]；
+// 字段中的范型参数替换为类型参数后，与普通结构体一样参与构造，注入和swagger生成；
+type InstanceType struct {
+	Typer
+	TypeArgs []Typer
+	fields   []*Field
+}
+
+func newInstanceType(x ast.Expr, indices []ast.Expr, goSource *Gosourse, typeMap map[string]*Field) Typer {
+	origin := parseType(x, goSource, typeMap)
+	if origin == nil {
+		return nil
+	}
+	instance := &InstanceType{Typer: origin}
+	for _, index := range indices {
+		instance.TypeArgs = append(instance.TypeArgs, parseType(index, goSource, typeMap))
+	}
+	return instance
+}
+
+func (t *InstanceType) typeArgs(name func(Typer) string) string {
+	var args []string
+	for _, arg := range t.TypeArgs {
+		args = append(args, anyTypeName(arg, name))
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+func (t *InstanceType) RefName(genFile *GenedFile) string {
+	return t.Typer.RefName(genFile) + t.typeArgs(func(arg Typer) string {
+		return arg.RefName(genFile)
+	})
+}
+
+func (t *InstanceType) IDName() string {
+	return t.Typer.IDName() + t.typeArgs(Typer.IDName)
+}
+
+// Fields 返回实例化后的字段，字段中的范型参数替换为类型参数
+func (t *InstanceType) Fields() []*Field {
+	if t.fields != nil {
+		return t.fields
+	}
+	class, ok := t.Typer.(*Struct)
+	if !ok {
+		return nil
+	}
+	t.fields = t.bindFields(class.Fields)
+	return t.fields
+}
+
+func (t *InstanceType) bindFields(fields []*Field) []*Field {
+	result := make([]*Field, 0, len(fields))
+	for _, field := range fields {
+		bound := *field
+		bound.Type = t.bind(field.Type)
+		result = append(result, &bound)
+	}
+	return result
+}
+
+// bind 将类型中的范型参数替换为类型参数
+func (t *InstanceType) bind(typer Typer) Typer {
+	switch typer := typer.(type) {
+	case *TypeParam:
+		if typer.Index < len(t.TypeArgs) {
+			return t.TypeArgs[typer.Index]
+		}
+	case *PointerType:
+		if elem := t.bind(typer.Typer); elem != nil {
+			return NewPointerType(elem)
+		}
+		return nil
+	case *ArrayType:
+		return &ArrayType{Typer: t.bind(typer.Typer)}
+	case *MapType:
+		return &MapType{
+			BaseType:   typer.BaseType,
+			KeyTyper:   t.bind(typer.KeyTyper),
+			ValueTyper: t.bind(typer.ValueTyper),
+		}
+	case *InstanceType:
+		instance := &InstanceType{Typer: typer.Typer}
+		for _, arg := range typer.TypeArgs {
+			instance.TypeArgs = append(instance.TypeArgs, t.bind(arg))
+		}
+		return instance
+	case *AnonymousStruct:
+		return &AnonymousStruct{
+			goSource: typer.goSource,
+			Fields:   t.bindFields(typer.Fields),
+		}
+	}
+	return typer
+}
+
+// GenConstructCode 结构体生成pkg.Name[Args]{...}，字段按照实例化后的类型注入
+func (t *InstanceType) GenConstructCode(genFile *GenedFile, wire bool) string {
+	if _, ok := t.Typer.(*Struct); !ok {
+		return t.Typer.GenConstructCode(genFile, wire)
+	}
+	var sb strings.Builder
+	sb.WriteString(t.RefName(genFile) + "{\n")
+	genFieldsConstructCode(&sb, t.Fields(), genFile, wire)
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (t *InstanceType) GenNilCode(file *GenedFile) string {
+	return genTypeNilCode(t, file, make(map[string]bool))
+}
@@ -11,15 +11,16 @@ type interfaceComments struct {
 	Type string
 }
 
-func (config *interfaceComments) dealValuePair(key, value string) {
+func (config *interfaceComments) dealValuePair(key, value string) error {
 	switch key {
 	case Host:
 		config.Host = value
 	case Type:
 		config.Type = strings.Trim(value, `"`)
 	default:
-		fmt.Printf("unkonw key value pair => key=%s,value=%s\n", key, value)
+		return fmt.Errorf("unknown key value pair => key=%s,value=%s", key, value)
 	}
+	return nil
 }
 
 type Interface struct {
@@ -45,7 +46,7 @@ func NewInterface(goSource *Gosourse, astRoot *ast.TypeSpec) *Interface {
 	return iface
 }
 func (i *Interface) Parse() error {
-	parseComment(i.astRoot.Doc, &i.Comment)
+	parseComment(i.astRoot.Doc, &i.Comment, i.GoSource)
 	// Type 为空表示不是client interface，跳过处理
 	if i.Comment.Type == "" {
 		return nil
@@ -1,10 +1,9 @@
 package astinfo
 
 import (
+	"fmt"
 	"go/ast"
 	"strings"
-
-	"github.com/go-openapi/spec"
 )
 
 // @goservlet prpc=xxx; servlet=xxx; servlet; prpc
@@ -15,9 +14,11 @@ type structComment struct {
 	serverType string // NONE, RpcStruct, ServletStruct·
 	Url        string // 服务的url, 对所有的方法都有效
 	AutoGen    bool
+	Tag        string // swagger中的tag，默认为结构体名
+	Doc        string // 注释中@gos之外的内容，作为swagger中tag的描述
 }
 
-func (comment *structComment) dealValuePair(key, value string) {
+func (comment *structComment) dealValuePair(key, value string) error {
 	if value != "" {
 		value = strings.Trim(value, "\"")
 	}
@@ -46,9 +47,12 @@ func (comment *structComment) dealValuePair(key, value string) {
 		}
 	case Url:
 		comment.Url = value
+	case Tag:
+		comment.Tag = value
 	case AutoGen:
 		comment.AutoGen = true
 	}
+	return nil
 }
 
 // Struct 表示一个Go结构体的基本信息
@@ -65,7 +69,6 @@ type Struct struct {
 	// FieldMap      map[string]*Field
 	MethodManager
 	// TODO: 后续添加字段和方法解析
-	ref *spec.Ref
 }
 
 func (v *Struct) RefName(genFile *GenedFile) string {
@@ -114,9 +117,17 @@ func (v *Struct) GenConstructCode(genFile *GenedFile, wire bool) string {
 	// getAddr(Strurct{
 	//}
 	//)
-	// 1. 有default，则wire；
-	// 2. wire为ture，且不是简单结构体（needWire），则寻找值去绑定；
-	for _, field := range v.Fields {
+	genFieldsConstructCode(&sb, v.Fields, genFile, wire)
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+// genFieldsConstructCode 生成结构体字面量中字段的赋值
+// 1. 有default，则wire；
+// 2. wire为ture，且不是简单结构体（needWire），则寻找值去绑定；
+func genFieldsConstructCode(sb *strings.Builder, fields []*Field, genFile *GenedFile, wire bool) {
+	for _, field := range fields {
 		v, ok := field.Tags["default"]
 		if ok || (needWire(field) && wire) {
 			sb.WriteString(field.Name + ":")
@@ -129,9 +140,6 @@ func (v *Struct) GenConstructCode(genFile *GenedFile, wire bool) string {
 			sb.WriteString(",\n")
 		}
 	}
-	sb.WriteString("}")
-
-	return sb.String()
 }
 
 // RequiredFields 返回结构体自己的字段，过滤掉原始类型或wire标记为"-"的字段
@@ -146,6 +154,39 @@ func (v *Struct) RequiredFields() []*Field {
 	return requiredFields
 }
 
+// TaggedFields 返回带有tag的字段，包括匿名嵌入结构体中的字段
+func (v *Struct) TaggedFields(tag string) []*Field {
+	return TaggedFields(v.Fields, tag)
+}
+
+// TaggedFields 返回fields中带有tag的字段，包括匿名嵌入结构体中的字段
+func TaggedFields(fields []*Field, tag string) []*Field {
+	var result []*Field
+	for _, field := range fields {
+		if field.Name == "" {
+			result = append(result, TaggedFields(StructFields(GetBasicType(field.Type)), tag)...)
+			continue
+		}
+		if _, ok := field.Tags[tag]; ok {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// StructFields 返回结构体，匿名结构体和范型结构体实例化后的字段，其他类型返回nil
+func StructFields(typer Typer) []*Field {
+	switch typer := typer.(type) {
+	case *Struct:
+		return typer.Fields
+	case *AnonymousStruct:
+		return typer.Fields
+	case *InstanceType:
+		return typer.Fields()
+	}
+	return nil
+}
+
 // GeneredFields 返回结构体自己
 func (v *Struct) GeneredFields() []*Field {
 	// 创建一个表示结构体自身的变量，且是指针格式；
@@ -153,22 +194,73 @@ func (v *Struct) GeneredFields() []*Field {
 	return []*Field{field}
 }
 func (field *Struct) GenNilCode(file *GenedFile) string {
+	return genTypeNilCode(field, file, make(map[string]bool))
+}
+
+// genTypeNilCode 生成将类型为nt的变量a中为nil的数组初始化为空数组的代码，包括嵌套的结构体中的数组
+func genTypeNilCode(nt Typer, file *GenedFile, visiting map[string]bool) string {
+	switch nt.(type) {
+	case *Struct, *AnonymousStruct, *InstanceType:
+		// time.Time等常用类型的字段都是不可导出的，不需要处理
+		if _, ok := wellKnownSchemas[nt.IDName()]; ok {
+			return ""
+		}
+		name := nt.IDName()
+		if visiting[name] {
+			return ""
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+		return genFieldsNilCode(StructFields(nt), file, visiting)
+	case *ArrayType:
+		return fmt.Sprintf("if *a == nil {\n*a = %s{}\n}\n", nt.RefName(file))
+	}
+	return ""
+}
+
+// genFieldsNilCode 生成结构体字段的初始化代码；生成的代码在gen包中，不能访问不可导出的字段；
+// 字段中没有需要初始化的数组时不生成代码块，否则a未被使用，编译失败
+func genFieldsNilCode(fields []*Field, file *GenedFile, visiting map[string]bool) string {
 	var sb strings.Builder
-	for _, f := range field.Fields {
+	for _, f := range fields {
+		name := f.Name
+		if name == "" {
+			name = embeddedFieldName(f.Type)
+		}
+		if !ast.IsExported(name) {
+			continue
+		}
+		var ref string
 		switch f.Type.(type) {
-		case *ArrayType, *Struct:
-			sb.WriteString("{\na:=&a." + f.Name + "\n")
-			sb.WriteString(f.GenNilCode(file))
-			sb.WriteString("}\n")
+		case *ArrayType, *Struct, *AnonymousStruct, *InstanceType:
+			ref = "&a."
 		case *PointerType:
-			sb.WriteString("{\na:=a." + f.Name + "\n")
-			sb.WriteString(f.GenNilCode(file))
-			sb.WriteString("}\n")
+			ref = "a."
+		default:
+			continue
+		}
+		code := f.genNilCode(file, visiting)
+		if code == "" {
+			continue
 		}
+		sb.WriteString("{\na:=" + ref + name + "\n")
+		sb.WriteString(code)
+		sb.WriteString("}\n")
 	}
 	return sb.String()
 }
 
+// embeddedFieldName 匿名字段的字段名为类型名，不是结构体时返回空字符串
+func embeddedFieldName(typer Typer) string {
+	switch typer := GetBasicType(typer).(type) {
+	case *Struct:
+		return typer.StructName
+	case *InstanceType:
+		return embeddedFieldName(typer.Typer)
+	}
+	return ""
+}
+
 // GenerateDependcyCode 生成创建结构体对象的代码
 func (v *Struct) GenerateDependcyCode(goGenerated *GenedFile) string {
 	a := v.GeneredFields()[0]
@@ -210,15 +302,14 @@ func (v *Struct) Parse() error {
 	if err := v.ParseComment(); err != nil {
 		return err
 	}
-	if v.astRoot.TypeParams != nil {
-		v.TypeParameter = parseFields(v.astRoot.TypeParams.List, v.goSource, nil)
-	}
+	v.TypeParameter = parseTypeParams(v.astRoot.TypeParams, v.goSource)
 	return v.ParseField()
 }
 
 // parseComment
 func (class *Struct) ParseComment() error {
-	parseComment(class.astRoot.Doc, &class.Comment)
+	parseComment(class.astRoot.Doc, &class.Comment, class.goSource)
+	class.Comment.Doc = docText(class.astRoot.Doc)
 	return nil
 }
 
@@ -0,0 +1,246 @@
+package astinfo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// 解析方式，通过-loader参数或配置中的Loader指定
+const (
+	LoaderAst      = "ast"      // 默认，按照import和go.mod自行定位package并解析类型
+	LoaderPackages = "packages" // 使用go/packages加载，类型由go/types确定
+)
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax |
+	packages.NeedTypesInfo | packages.NeedModule
+
+// loadPackages 使用go/packages加载项目及其依赖，并注册到Packages中；
+// 之后FindPackage直接返回这些package，Gosourse解析时从typesInfo中获取类型；
+func (mp *MainProject) loadPackages(p *Project) error {
+	env, buildFlags := mp.buildFlags()
+	cfg := &packages.Config{
+		Mode:       loadMode,
+		Dir:        p.Path,
+		Fset:       token.NewFileSet(),
+		Env:        env,
+		BuildFlags: buildFlags,
+	}
+	roots, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return fmt.Errorf("load packages failed: %w", err)
+	}
+	genModule := p.Module + "/gen"
+	packages.Visit(roots, nil, func(loaded *packages.Package) {
+		// gen目录下是生成的代码，不参与解析；引用gen的package在生成代码前可能编译不过，不打印错误
+		if loaded.PkgPath == genModule || strings.HasPrefix(loaded.PkgPath, genModule+"/") {
+			return
+		}
+		if _, ok := loaded.Imports[genModule]; !ok {
+			for _, err := range loaded.Errors {
+				Warnf(errorPosition(err.Pos), "%s", err.Msg)
+			}
+		}
+		if mp.Packages[loaded.PkgPath] != nil || loaded.Types == nil {
+			return
+		}
+		dir := p.Path
+		if len(loaded.GoFiles) > 0 {
+			dir = filepath.Dir(loaded.GoFiles[0])
+		}
+		main := loaded.Module != nil && loaded.Module.Main
+		pkg := NewPackage(loaded.PkgPath, !main, dir)
+		pkg.Name = loaded.Name
+		pkg.fset = cfg.Fset
+		pkg.Files = make(map[string]*ast.File)
+		for i, file := range loaded.Syntax {
+			if i < len(loaded.CompiledGoFiles) {
+				pkg.Files[loaded.CompiledGoFiles[i]] = file
+			}
+		}
+		pkg.typesInfo = loaded.TypesInfo
+		mp.Packages[loaded.PkgPath] = pkg
+	})
+	return nil
+}
+
+// errorPosition 解析packages.Error中file:line:col格式的位置
+func errorPosition(pos string) token.Position {
+	var position token.Position
+	if pos == "" || pos == "-" {
+		return position
+	}
+	position.Filename = pos
+	// 文件名中可能包含冒号，从后向前解析行号和列号
+	for _, target := range []*int{&position.Column, &position.Line} {
+		index := strings.LastIndexByte(position.Filename, ':')
+		if index < 0 {
+			break
+		}
+		n, err := strconv.Atoi(position.Filename[index+1:])
+		if err != nil {
+			break
+		}
+		*target = n
+		position.Filename = position.Filename[:index]
+	}
+	if position.Line == 0 {
+		// 只有行号，没有列号
+		position.Line, position.Column = position.Column, 0
+	}
+	return position
+}
+
+// typeOf 从go/types中获取表达式的类型，并转换为Typer；
+// typeMap为结构体的范型参数，范型参数使用其约束的类型，与ast的解析方式一致；
+func typeOf(expr ast.Expr, info *types.Info, typeMap map[string]*Field) (Typer, bool) {
+	t := info.TypeOf(expr)
+	if t == nil {
+		return nil, false
+	}
+	return typerOf(t, typeMap), true
+}
+
+// typerOf 将go/types中的类型转换为Typer；无法表示的类型（如函数内定义的类型）返回nil
+func typerOf(t types.Type, typeMap map[string]*Field) Typer {
+	switch t := t.(type) {
+	case *types.Basic:
+		if raw := GetRawType(t.Name()); raw != nil {
+			return raw
+		}
+	case *types.Pointer:
+		if elem := typerOf(t.Elem(), typeMap); elem != nil {
+			return NewPointerType(elem)
+		}
+	case *types.Slice:
+		return &ArrayType{Typer: typerOf(t.Elem(), typeMap)}
+	case *types.Array:
+		return &ArrayType{Typer: typerOf(t.Elem(), typeMap)}
+	case *types.Map:
+		return &MapType{
+			BaseType:   BaseType{typeName: "map"},
+			KeyTyper:   typerOf(t.Key(), typeMap),
+			ValueTyper: typerOf(t.Elem(), typeMap),
+		}
+	case *types.Alias:
+		return namedTyper(t.Obj(), t.TypeArgs(), typeMap)
+	case *types.Named:
+		return namedTyper(t.Obj(), t.TypeArgs(), typeMap)
+	case *types.TypeParam:
+		if param := typeMap[t.Obj().Name()]; param != nil {
+			return param.Type
+		}
+	case *types.Interface:
+		return interfaceTyper(t, typeMap)
+	case *types.Signature:
+		return &FuncType{
+			Params:   tupleTypers(t.Params(), typeMap),
+			Results:  tupleTypers(t.Results(), typeMap),
+			Variadic: t.Variadic(),
+		}
+	case *types.Chan:
+		dir := ast.SEND | ast.RECV
+		switch t.Dir() {
+		case types.SendOnly:
+			dir = ast.SEND
+		case types.RecvOnly:
+			dir = ast.RECV
+		}
+		return &ChanType{Dir: dir, Elem: typerOf(t.Elem(), typeMap)}
+	}
+	return nil
+}
+
+// interfaceTyper 空interface为any，否则与ast的解析方式一致，返回AnonymousInterface
+func interfaceTyper(t *types.Interface, typeMap map[string]*Field) Typer {
+	if t.Empty() {
+		return GetRawType("any")
+	}
+	iface := &AnonymousInterface{}
+	for i := range t.NumEmbeddeds() {
+		iface.Embeds = append(iface.Embeds, typerOf(t.EmbeddedType(i), typeMap))
+	}
+	for i := range t.NumExplicitMethods() {
+		method := t.ExplicitMethod(i)
+		signature := method.Type().(*types.Signature)
+		iface.Methods = append(iface.Methods, &InterfaceField{
+			FunctionField: FunctionField{
+				Name:    method.Name(),
+				Params:  tupleFields(signature.Params(), typeMap),
+				Results: tupleFields(signature.Results(), typeMap),
+			},
+		})
+	}
+	return iface
+}
+
+// tupleTypers 返回函数参数或返回值的类型
+func tupleTypers(tuple *types.Tuple, typeMap map[string]*Field) []Typer {
+	var typers []Typer
+	for i := range tuple.Len() {
+		typers = append(typers, typerOf(tuple.At(i).Type(), typeMap))
+	}
+	return typers
+}
+
+func tupleFields(tuple *types.Tuple, typeMap map[string]*Field) []*Field {
+	var fields []*Field
+	for i := range tuple.Len() {
+		field := &Field{}
+		field.Name = tuple.At(i).Name()
+		field.Type = typerOf(tuple.At(i).Type(), typeMap)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// namedTyper 在对应的package中寻找类型定义；带类型参数时返回InstanceType
+func namedTyper(obj *types.TypeName, typeArgs *types.TypeList, typeMap map[string]*Field) Typer {
+	if obj.Pkg() == nil {
+		// error，any，comparable
+		return GetRawType(obj.Name())
+	}
+	// 函数内定义的类型不在package中
+	if obj.Parent() != obj.Pkg().Scope() {
+		return nil
+	}
+	typer := GlobalProject.FindPackage(obj.Pkg().Path()).GetTyper(obj.Name())
+	if typer == nil || typeArgs.Len() == 0 {
+		return typer
+	}
+	instance := &InstanceType{Typer: typer}
+	for i := range typeArgs.Len() {
+		instance.TypeArgs = append(instance.TypeArgs, typerOf(typeArgs.At(i), typeMap))
+	}
+	return instance
+}
+
+// receiverOf 通过go/types获取方法的receiver；receiver为别名时返回其指向的结构体
+func receiverOf(funcDecl *ast.FuncDecl, info *types.Info) *Struct {
+	fun, ok := info.Defs[funcDecl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	recv := fun.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	t := recv.Type()
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return nil
+	}
+	obj := named.Origin().Obj()
+	class, _ := GlobalProject.FindPackage(obj.Pkg().Path()).GetTyper(obj.Name()).(*Struct)
+	return class
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wanjm/gos/astinfo"
+)
+
+// check 实现gos check，在内存中解析和生成代码，与磁盘上的文件比较，不写入任何生成的文件；
+// 返回值作为退出码：0生成的文件都是最新的，1有文件需要重新生成，2执行失败；
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	var path, loader string
+	flags.StringVar(&path, "p", ".", "需要检查的工程的根目录")
+	flags.StringVar(&loader, "loader", "", "解析方式，ast或packages，默认使用配置中的Loader")
+	logLevel := flags.String("log-level", "error", "输出的诊断信息的最低级别，error，warning或info")
+	quiet := flags.Bool("q", false, "只输出需要重新生成的文件名，不输出diff")
+	flags.Parse(args)
+	if err := setupDiagnostics(*logLevel, "text"); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	// 诊断信息和生成过程中的日志输出到stderr，保证stdout中只有diff
+	astinfo.Diagnostics.Output = os.Stderr
+	output := astinfo.NewMemoryOutput()
+	astinfo.GenOutput = output
+	project, err := loadProject(path, "", loader, cacheReadOnly)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	project.Cfg.NoPublish = true
+	err = project.GenerateCode()
+	astinfo.Diagnostics.Summary(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generate code failed with %s\n", err.Error())
+		return 2
+	}
+	// 有error时生成的结果不完整，比较没有意义
+	if astinfo.Diagnostics.HasErrors() {
+		return 2
+	}
+	// loadProject已经切换到工程根目录
+	root, _ := os.Getwd()
+	changes := output.Changes()
+	for _, change := range changes {
+		name, err := filepath.Rel(root, change.Name)
+		if err != nil {
+			name = change.Name
+		}
+		name = filepath.ToSlash(name)
+		if *quiet {
+			fmt.Fprintln(os.Stdout, name)
+			continue
+		}
+		writeUnifiedDiff(os.Stdout, name, change.Old, change.New)
+	}
+	if len(changes) > 0 {
+		fmt.Fprintf(os.Stderr, "%d generated file(s) are out of date, run gos to regenerate\n", len(changes))
+		return 1
+	}
+	return 0
+}
+
+// diffContext unified diff中每个修改前后保留的行数，与diff -u一致
+const diffContext = 3
+
+// writeUnifiedDiff 输出old到new的unified diff；old为nil表示新增的文件，new为nil表示删除的文件
+func writeUnifiedDiff(w io.Writer, name string, old, new []byte) {
+	oldName, newName := "a/"+name, "b/"+name
+	if old == nil {
+		oldName = "/dev/null"
+	}
+	if new == nil {
+		newName = "/dev/null"
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName)
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+	// 按照diffContext将修改分成多个hunk
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		first := max(start-diffContext, 0)
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		last := min(end+diffContext, len(ops))
+		hunk := ops[first:last]
+		oldStart, newStart := hunk[0].oldLine, hunk[0].newLine
+		var oldCount, newCount int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range hunk {
+			fmt.Fprintf(w, "%c%s", op.kind, op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				fmt.Fprint(w, "\n\\ No newline at end of file\n")
+			}
+		}
+		start = last
+	}
+}
+
+// hunkRange 输出hunk的起始行和行数；行数为0时起始行为修改位置的前一行
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines 按行分割，每行保留结尾的换行；最后一行没有换行时保持原样
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp diff的一行；kind为' '，'-'或'+'，oldLine和newLine为该行在两个文件中的行号(从1开始)
+type diffOp struct {
+	kind    byte
+	text    string
+	oldLine int
+	newLine int
+}
+
+// diffLines 使用Myers算法计算a到b的最短编辑序列
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d]保存第d步开始前的v[-d..d]，用于回溯
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+	// 从终点回溯得到编辑序列
+	var reversed []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		prev := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		}
+		prevX, prevY := 0, 0
+		if d > 0 {
+			prevX = prev(prevK)
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffOp{kind: ' ', text: a[x], oldLine: x + 1, newLine: y + 1})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, diffOp{kind: '+', text: b[y], oldLine: x + 1, newLine: y + 1})
+		} else {
+			x--
+			reversed = append(reversed, diffOp{kind: '-', text: a[x], oldLine: x + 1, newLine: y + 1})
+		}
+	}
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
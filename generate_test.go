@@ -0,0 +1,477 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wanjm/gos/astinfo"
+)
+
+// writeProject 将files写入dir，key为相对dir的文件名
+func writeProject(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// copyDir 将src复制到dst，跳过解析缓存
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".gos" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0750)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0660)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// generateProject 在进程内解析dir并生成代码，写入astinfo.GenOutput；有error级别的诊断信息时失败
+func generateProject(t *testing.T, dir, modName, loader string, cache cacheMode) {
+	t.Helper()
+	// loadProject会切换到工程目录
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	astinfo.Diagnostics = &astinfo.DiagnosticCollector{Level: astinfo.SeverityError}
+	project, err := loadProject(dir, modName, loader, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := project.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	for _, diagnostic := range astinfo.Diagnostics.List() {
+		t.Error(diagnostic.String())
+	}
+}
+
+// goCommand 在dir中执行go命令
+func goCommand(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
+
+// nilCodeProject 返回值中有匿名结构体，time.Time，不可导出的字段，匿名字段和自引用的指针，
+// 只有包含数组的字段才生成初始化代码，否则生成的代码中a未被使用
+var nilCodeProject = map[string]string{
+	"api/api.go": `package api
+
+import (
+	"context"
+	"time"
+)
+
+// Api 接口
+// @gos type=servlet; url="/api"
+type Api struct{}
+
+type Base struct {
+	Tags []string ` + "`json:\"tags\"`" + `
+}
+
+type Resp struct {
+	Base
+	Items  []string ` + "`json:\"items\"`" + `
+	Inline struct {
+		Names []string ` + "`json:\"names\"`" + `
+		Count int      ` + "`json:\"count\"`" + `
+	} ` + "`json:\"inline\"`" + `
+	Plain struct {
+		Count int ` + "`json:\"count\"`" + `
+	} ` + "`json:\"plain\"`" + `
+	Created time.Time ` + "`json:\"created\"`" + `
+	Next    *Resp     ` + "`json:\"next\"`" + `
+	hidden  []int
+}
+
+type Req struct {
+	Id int ` + "`json:\"id\"`" + `
+}
+
+// @gos url="/get"
+func (a *Api) Get(ctx context.Context, req *Req) (*Resp, error) {
+	return &Resp{hidden: nil}, nil
+}
+`,
+}
+
+func TestGenerateNilCodeCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated project")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	for _, loader := range []string{astinfo.LoaderAst, astinfo.LoaderPackages} {
+		t.Run(loader, func(t *testing.T) {
+			dir := t.TempDir()
+			writeProject(t, dir, nilCodeProject)
+			// -i 同时生成go.mod，main.go和basic
+			generateProject(t, dir, "example.com/niltest", loader, cacheDefault)
+			if t.Failed() {
+				return
+			}
+			buildGenerated(t, dir, "servlet.go")
+		})
+	}
+}
+
+// buildGenerated 整理依赖后编译dir，依赖无法下载时跳过；失败时打印gen目录中的genFile
+func buildGenerated(t *testing.T, dir, genFile string) {
+	t.Helper()
+	if output, err := goCommand(dir, "mod", "tidy"); err != nil {
+		t.Skipf("resolve dependencies of generated code failed: %s\n%s", err, output)
+	}
+	if output, err := goCommand(dir, "build", "./..."); err != nil {
+		content, _ := os.ReadFile(filepath.Join(dir, "gen", genFile))
+		t.Fatalf("build generated code failed: %s\n%s\n%s", err, output, content)
+	}
+}
+
+// clientProject 逗号分隔的多个host，服务发现和变量形式的host
+var clientProject = map[string]string{
+	"client/client.go": `package client
+
+import "context"
+
+var DefaultHost = "http://127.0.0.1:8080"
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+// @gos type=prpc; host="http://a, http://b"
+type UserClient interface {
+	// @gos url="/user/get"
+	GetUser(ctx context.Context, id int) (*User, error)
+}
+
+var Users UserClient
+
+// @gos type=prpc; host="discovery:note"
+type NoteClient interface {
+	// @gos url="/note/add"
+	Add(ctx context.Context, title string) error
+}
+
+var Notes NoteClient
+
+// @gos type=prpc; host=DefaultHost
+type TagClient interface {
+	// @gos url="/tag/list"
+	List(ctx context.Context) ([]string, error)
+}
+
+var Tags TagClient
+`,
+}
+
+func TestGenerateClientCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated project")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	writeProject(t, dir, clientProject)
+	generateProject(t, dir, "example.com/clienttest", astinfo.LoaderAst, cacheDefault)
+	if t.Failed() {
+		return
+	}
+	client, err := os.ReadFile(filepath.Join(dir, "gen", "rpc_client_prpc.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`NewStaticResolver("http://a", "http://b")`,
+		`&discoveryResolver{name: "note"}`,
+		`NewStaticResolver(client.DefaultHost)`,
+	} {
+		if !strings.Contains(string(client), want) {
+			t.Errorf("%s is not generated", want)
+		}
+	}
+	buildGenerated(t, dir, "rpc_client_prpc.go")
+}
+
+// generateToMemory 生成代码到内存中，返回生成的所有文件
+func generateToMemory(t *testing.T, dir, loader string, cache cacheMode) map[string][]byte {
+	t.Helper()
+	output := astinfo.NewMemoryOutput()
+	astinfo.GenOutput = output
+	defer func() {
+		astinfo.GenOutput = astinfo.DiskOutput{}
+	}()
+	generateProject(t, dir, "", loader, cache)
+	return output.Files()
+}
+
+// TestGenerateDeterministic 对example生成两次，结果必须完全相同；
+// 第一次清除缓存重新解析，第二次使用第一次的缓存，同时检查缓存与重新解析的结果一致
+func TestGenerateDeterministic(t *testing.T) {
+	for _, loader := range []string{astinfo.LoaderAst, astinfo.LoaderPackages} {
+		t.Run(loader, func(t *testing.T) {
+			if loader == astinfo.LoaderPackages {
+				if testing.Short() {
+					t.Skip("loads packages with go list")
+				}
+				if _, err := exec.LookPath("go"); err != nil {
+					t.Skip("go command not found")
+				}
+			}
+			dir := t.TempDir()
+			copyDir(t, "example", dir)
+			first := generateToMemory(t, dir, loader, cacheForce)
+			second := generateToMemory(t, dir, loader, cacheDefault)
+			if t.Failed() {
+				return
+			}
+			if len(first) == 0 {
+				t.Fatal("no file generated")
+			}
+			for name, content := range first {
+				if other, ok := second[name]; !ok {
+					t.Errorf("%s is only generated in the first run", name)
+				} else if string(content) != string(other) {
+					t.Errorf("%s is not deterministic", name)
+				}
+			}
+			for name := range second {
+				if _, ok := first[name]; !ok {
+					t.Errorf("%s is only generated in the second run", name)
+				}
+			}
+		})
+	}
+}
+
+// workspaceProject go.work中的lib模块require了third，app只依赖lib；third只能通过lib的require和replace找到
+var workspaceProject = map[string]string{
+	"go.work":      "go 1.23\n\nuse (\n\t./app\n\t./lib\n)\n",
+	"app/go.mod":   "module example.com/app\n\ngo 1.23\n",
+	"lib/go.mod":   "module example.com/lib\n\ngo 1.23\n\nrequire example.com/third v1.0.0\n\nreplace example.com/third => ../third\n",
+	"third/go.mod": "module example.com/third\n\ngo 1.23\n",
+	"third/third.go": `package third
+
+type Item struct {
+	Title string ` + "`json:\"title\"`" + `
+}
+`,
+	"lib/lib.go": `package lib
+
+import "example.com/third"
+
+type Resp struct {
+	Item third.Item ` + "`json:\"item\"`" + `
+}
+`,
+	"app/api/api.go": `package api
+
+import (
+	"context"
+
+	"example.com/lib"
+)
+
+// Api 接口
+// @gos type=servlet; url="/api"
+type Api struct{}
+
+type Req struct {
+	Id int ` + "`json:\"id\"`" + `
+}
+
+// @gos url="/get"
+func (a *Api) Get(ctx context.Context, req *Req) (*lib.Resp, error) {
+	return nil, nil
+}
+`,
+}
+
+func TestGenerateWorkspaceUseRequires(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir, workspaceProject)
+	app := filepath.Join(dir, "app")
+	files := generateToMemory(t, app, astinfo.LoaderAst, cacheDefault)
+	if t.Failed() {
+		return
+	}
+	var doc struct {
+		Definitions map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(files[filepath.Join(app, "swagger.json")], &doc); err != nil {
+		t.Fatal(err)
+	}
+	item, ok := doc.Definitions["third.Item"]
+	if !ok || item.Properties["title"] == nil {
+		t.Errorf("third.Item is not resolved from the require of lib: %v", doc.Definitions)
+	}
+	if warnings := astinfo.Diagnostics.Count(astinfo.SeverityWarning); warnings != 0 {
+		t.Errorf("got %d warning(s)", warnings)
+	}
+}
+
+// funcFieldProject 返回值中有函数，chan和非空interface类型的字段
+var funcFieldProject = map[string]string{
+	"go.mod": "module example.com/functest\n\ngo 1.23\n",
+	"api/api.go": `package api
+
+import "context"
+
+// Api 接口
+// @gos type=servlet; url="/api"
+type Api struct{}
+
+type Handler interface {
+	Handle(ctx context.Context) error
+}
+
+type Req struct {
+	Id int ` + "`json:\"id\"`" + `
+}
+
+type Resp struct {
+	Id      int ` + "`json:\"id\"`" + `
+	OnDone  func(ctx context.Context, names ...string) (int, error)
+	Events  <-chan Req
+	Handler Handler
+}
+
+// @gos url="/get"
+func (a *Api) Get(ctx context.Context, req *Req) (*Resp, error) {
+	return nil, nil
+}
+`,
+}
+
+// TestLoaderFuncFields 函数，chan和非空interface字段解析为对应的Typer，swagger中跳过函数和chan
+func TestLoaderFuncFields(t *testing.T) {
+	for _, loader := range []string{astinfo.LoaderAst, astinfo.LoaderPackages} {
+		t.Run(loader, func(t *testing.T) {
+			if loader == astinfo.LoaderPackages {
+				if testing.Short() {
+					t.Skip("loads packages with go list")
+				}
+				if _, err := exec.LookPath("go"); err != nil {
+					t.Skip("go command not found")
+				}
+			}
+			dir := t.TempDir()
+			writeProject(t, dir, funcFieldProject)
+			files := generateToMemory(t, dir, loader, cacheForce)
+			if t.Failed() {
+				return
+			}
+			resp, ok := astinfo.GlobalProject.FindPackage("example.com/functest/api").GetTyper("Resp").(*astinfo.Struct)
+			if !ok {
+				t.Fatal("Resp is not parsed")
+			}
+			want := map[string]string{
+				"Id":      "int",
+				"OnDone":  "func(context.Context, ...string) (int, error)",
+				"Events":  "<-chan example.com/functest/api.Req",
+				"Handler": "example.com/functest/api.Handler",
+			}
+			for _, field := range resp.Fields {
+				if field.Type == nil {
+					t.Errorf("type of %s is not resolved", field.Name)
+				} else if got := field.Type.IDName(); got != want[field.Name] {
+					t.Errorf("type of %s = %s, want %s", field.Name, got, want[field.Name])
+				}
+			}
+			var doc struct {
+				Definitions map[string]struct {
+					Properties map[string]any `json:"properties"`
+				} `json:"definitions"`
+			}
+			if err := json.Unmarshal(files[filepath.Join(dir, "swagger.json")], &doc); err != nil {
+				t.Fatal(err)
+			}
+			properties := doc.Definitions["api.Resp"].Properties
+			for _, name := range []string{"id", "handler"} {
+				if properties[name] == nil {
+					t.Errorf("property %s is missing: %v", name, properties)
+				}
+			}
+			for _, name := range []string{"onDone", "events"} {
+				if properties[name] != nil {
+					t.Errorf("property %s should be skipped", name)
+				}
+			}
+		})
+	}
+}
+
+// mockProject 参数名与mock方法中使用的名字(m，fn，r0)，Assert和Mock函数的参数t，以及大小写不同的参数重名
+var mockProject = map[string]string{
+	"go.mod": "module example.com/mocktest\n\ngo 1.23\n",
+	"client/client.go": `package client
+
+import "context"
+
+// @gos type=prpc; host="http://a"
+type UserClient interface {
+	// @gos url="/user/get"
+	Get(ctx context.Context, t int, m string, fn []string) (int, error)
+	// @gos url="/user/set"
+	Set(ctx context.Context, a int, A int, r0 string) error
+}
+
+var Users UserClient
+`,
+}
+
+func TestGenerateMocksCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated mocks")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	writeProject(t, dir, mockProject)
+	generateProject(t, dir, "", astinfo.LoaderAst, cacheDefault)
+	if t.Failed() {
+		return
+	}
+	if output, err := goCommand(dir, "vet", "./gen/mocks"); err != nil {
+		mocks, _ := os.ReadFile(filepath.Join(dir, "gen", "mocks", "mocks.go"))
+		t.Fatalf("vet generated mocks failed: %s\n%s\n%s", err, output, mocks)
+	}
+}
@@ -8,6 +8,8 @@ require (
 	github.com/BurntSushi/toml v1.5.0
 	github.com/go-openapi/spec v0.21.0
 	golang.org/x/mod v0.26.0
+	golang.org/x/tools v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -16,5 +18,5 @@ require (
 	github.com/go-openapi/swag v0.23.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sync v0.16.0 // indirect
 )
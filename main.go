@@ -12,10 +12,26 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "apidiff":
+			os.Exit(apiDiff(os.Args[2:]))
+		case "check":
+			os.Exit(check(os.Args[2:]))
+		case "watch":
+			os.Exit(watch(os.Args[2:]))
+		}
+	}
 	var path string
 	flag.StringVar(&path, "p", ".", "需要生成代码工程的根目录")
 	var modName string
 	flag.StringVar(&modName, "i", "", "指定模块名称")
+	var loader string
+	flag.StringVar(&loader, "loader", "", "解析方式，ast或packages(使用go/packages和go/types解析类型)，默认使用配置中的Loader")
+	force := flag.Bool("force", false, "清除解析缓存(.gos/cache)，重新解析所有package")
+	var logLevel, diagFormat string
+	flag.StringVar(&logLevel, "log-level", "warning", "输出的诊断信息的最低级别，error，warning或info")
+	flag.StringVar(&diagFormat, "diag-format", "text", "诊断信息的输出格式，text或json(结束时输出JSON数组，供编辑器使用)")
 	h := flag.Bool("h", false, "显示帮助文件")
 	v := flag.Bool("v", false, "显示版本信息") // 添加-v参数
 	flag.Parse()
@@ -29,16 +45,72 @@ func main() {
 		flag.Usage()
 		return
 	}
+	if err := setupDiagnostics(logLevel, diagFormat); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	cache := cacheDefault
+	if *force {
+		cache = cacheForce
+	}
+	project, err := loadProject(path, modName, loader, cache)
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		os.Exit(1)
+	}
+	err = project.GenerateCode()
+	astinfo.Diagnostics.Summary(os.Stdout)
+	if err != nil {
+		fmt.Printf("generate code failed with %s\n", err.Error())
+		os.Exit(1)
+	}
+	if astinfo.Diagnostics.HasErrors() {
+		os.Exit(1)
+	}
+}
+
+// setupDiagnostics 根据-log-level和-diag-format设置诊断信息的输出
+func setupDiagnostics(logLevel, diagFormat string) error {
+	level, err := astinfo.ParseSeverity(logLevel)
+	if err != nil {
+		return err
+	}
+	astinfo.Diagnostics.Level = level
+	switch diagFormat {
+	case "text":
+	case "json":
+		astinfo.Diagnostics.JSON = true
+	default:
+		return fmt.Errorf("unknown diag-format %s, should be text or json", diagFormat)
+	}
+	return nil
+}
+
+// cacheMode 解析缓存(.gos/cache)的使用方式
+type cacheMode int
+
+const (
+	cacheDefault  cacheMode = iota
+	cacheForce              // -force，清除缓存，重新解析所有package
+	cacheReadOnly           // gos check，只读取缓存，不修改工程中的任何文件
+)
+
+// loadProject 读取path下的配置并解析工程
+func loadProject(path, modName, loader string, cache cacheMode) (*astinfo.MainProject, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
-		fmt.Printf("open %s failed with %s", path, err.Error())
-		return
+		return nil, fmt.Errorf("open %s failed with %s", path, err.Error())
 	}
 	os.Chdir(path)
 	cfg := astinfo.Config{
 		InitMain: modName, // 直接赋值模块名称
 	}
 	cfg.Load()
+	if loader != "" {
+		cfg.Loader = loader
+	}
+	cfg.ForceParse = cache == cacheForce
+	cfg.CacheRead = cache == cacheReadOnly
 	astinfo.RegisterCallableGen(callable_gen.NewServletGen(4, 1), &callable_gen.PrpcGen{}, &callable_gen.ResutfulGen{})
 	astinfo.RegisterClientGen(&rpcgen.PrpcGen{})
 	var project = astinfo.CreateProject(path, &cfg)
@@ -50,8 +122,7 @@ func main() {
 
 	err = project.Parse()
 	if err != nil {
-		fmt.Printf("parse project failed with %s", err.Error())
-		return
+		return nil, fmt.Errorf("parse project failed with %s", err.Error())
 	}
-	project.GenerateCode()
+	return project, nil
 }
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wanjm/gos/astinfo"
+)
+
+// watchFile 被监控的文件的状态；decl为声明部分的hash，只有decl变化时才需要重新生成代码
+type watchFile struct {
+	modTime time.Time
+	size    int64
+	decl    string
+}
+
+// watchChange 一次扫描发现的修改
+type watchChange struct {
+	files []string // 修改，新增或删除的文件，相对工程根目录
+	regen bool     // 声明，go.mod或配置文件有修改，需要重新生成代码
+}
+
+// watcher 定时扫描工程中的文件，第一次扫描只记录状态
+type watcher struct {
+	root  string
+	files map[string]*watchFile
+}
+
+// watchConfigFiles 修改后需要重新生成代码的非go文件
+var watchConfigFiles = map[string]bool{
+	"go.mod":               true,
+	"go.sum":               true,
+	"go.work":              true,
+	"project.public.toml":  true,
+	"project.private.toml": true,
+}
+
+// scan 扫描工程，返回与上一次扫描相比的修改；跳过的目录与解析工程时相同，测试文件不参与解析，也不监控
+func (w *watcher) scan() watchChange {
+	var change watchChange
+	seen := make(map[string]bool)
+	filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 扫描过程中被删除的文件或目录，下一次扫描时处理
+			return nil
+		}
+		if d.IsDir() {
+			if path != w.root && astinfo.SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		isGo := strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+		if !isGo && !(watchConfigFiles[name] && filepath.Dir(path) == w.root) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		seen[path] = true
+		old := w.files[path]
+		if old != nil && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		file := &watchFile{modTime: info.ModTime(), size: info.Size()}
+		if isGo {
+			file.decl = astinfo.DeclarationHash(path, content)
+		} else {
+			file.decl = string(content)
+		}
+		w.files[path] = file
+		change.add(w.root, path, old == nil || old.decl != file.decl)
+		return nil
+	})
+	var removed []string
+	for path := range w.files {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		delete(w.files, path)
+		change.add(w.root, path, true)
+	}
+	return change
+}
+
+func (c *watchChange) add(root, path string, regen bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	c.files = append(c.files, rel)
+	c.regen = c.regen || regen
+}
+
+// watch 实现gos watch，监控工程中的文件，声明修改后重新生成代码；-run时重新编译并重启服务
+func watch(args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	var path, loader, logLevel string
+	flags.StringVar(&path, "p", ".", "需要监控的工程的根目录")
+	flags.StringVar(&loader, "loader", "", "解析方式，ast或packages，默认使用配置中的Loader")
+	flags.StringVar(&logLevel, "log-level", "warning", "输出的诊断信息的最低级别，error，warning或info")
+	interval := flags.Duration("interval", 500*time.Millisecond, "扫描文件的间隔")
+	delay := flags.Duration("delay", 300*time.Millisecond, "最后一次修改之后等待的时间，编辑器保存多个文件时只生成一次")
+	run := flags.Bool("run", false, "生成代码后重新编译并重启服务，-- 之后的参数传给服务")
+	flags.Parse(args)
+
+	root, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "find gos executable failed with %s\n", err.Error())
+		return 2
+	}
+	// 每次在子进程中生成代码，解析结果通过.gos/cache复用；注册的生成器和GlobalProject等全局状态不需要重置
+	genArgs := []string{"-p", root, "-log-level", logLevel}
+	if loader != "" {
+		genArgs = append(genArgs, "-loader", loader)
+	}
+	generate := func() bool {
+		start := time.Now()
+		cmd := exec.Command(self, genArgs...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		err := cmd.Run()
+		if err != nil {
+			fmt.Printf("[gos watch] generate failed in %s: %s\n", time.Since(start).Round(time.Millisecond), err.Error())
+			return false
+		}
+		fmt.Printf("[gos watch] generated in %s\n", time.Since(start).Round(time.Millisecond))
+		return true
+	}
+	var app *watchApp
+	if *run {
+		app = &watchApp{root: root, args: flags.Args()}
+	}
+
+	w := &watcher{root: root, files: make(map[string]*watchFile)}
+	w.scan()
+	if generate() && app != nil {
+		app.restart()
+	}
+	fmt.Printf("[gos watch] watching %s\n", root)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	var pending watchChange
+	var lastChange time.Time
+	for {
+		select {
+		case <-signals:
+			if app != nil {
+				app.stop()
+			}
+			return 0
+		case <-ticker.C:
+		}
+		change := w.scan()
+		if len(change.files) > 0 {
+			pending.files = append(pending.files, change.files...)
+			pending.regen = pending.regen || change.regen
+			lastChange = time.Now()
+			continue
+		}
+		// 等待编辑器保存完所有文件
+		if len(pending.files) == 0 || time.Since(lastChange) < *delay {
+			continue
+		}
+		changed := pending
+		pending = watchChange{}
+		// 只修改了函数体时不需要重新生成代码，也不需要重启时忽略
+		if !changed.regen && app == nil {
+			continue
+		}
+		fmt.Printf("[gos watch] changed: %s\n", strings.Join(uniqueStrings(changed.files), ", "))
+		ok := true
+		if changed.regen {
+			ok = generate()
+		}
+		// 生成的代码有error时不重启，保留正在运行的服务
+		if ok && app != nil {
+			app.restart()
+		}
+	}
+}
+
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// watchApp -run时编译和运行的服务
+type watchApp struct {
+	root string
+	args []string // 传给服务的参数
+	cmd  *exec.Cmd
+}
+
+// restart 编译工程，成功后停止正在运行的服务并启动新的服务；编译失败时保留正在运行的服务
+func (app *watchApp) restart() {
+	dir := filepath.Join(app.root, ".gos", "watch")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		fmt.Printf("[gos watch] create %s failed: %s\n", dir, err.Error())
+		return
+	}
+	// 编译结果不需要提交到git
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0640)
+	binary := filepath.Join(dir, filepath.Base(app.root))
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = app.root
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Printf("[gos watch] build failed: %s\n", err.Error())
+		return
+	}
+	app.stop()
+	cmd := exec.Command(binary, app.args...)
+	cmd.Dir = app.root
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Printf("[gos watch] start %s failed: %s\n", binary, err.Error())
+		return
+	}
+	app.cmd = cmd
+	fmt.Printf("[gos watch] started %s (pid %d)\n", binary, cmd.Process.Pid)
+}
+
+// stop 先发送中断信号让服务正常退出，超时后强制结束
+func (app *watchApp) stop() {
+	if app.cmd == nil {
+		return
+	}
+	cmd := app.cmd
+	app.cmd = nil
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		<-done
+	}
+}